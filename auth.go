@@ -1,8 +1,11 @@
 package tiga
 
 import (
+	"crypto/hmac"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 )
 
 func GenerateJWT(payload interface{}, secret string) (string, error) {
@@ -24,3 +27,38 @@ func GenerateJWT(payload interface{}, secret string) (string, error) {
 	// 生成token
 	return fmt.Sprintf("%s.%s",sig,ComputeHmacSha256(sig, secret)) , nil
 }
+
+// ParseJWT 校验 GenerateJWT 生成的token并将 payload 解析到 claims，
+// payload 中的 exp(unix秒) 早于当前时间时返回错误
+func ParseJWT(token string, secret string, claims interface{}) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("invalid token")
+	}
+	sig := ComputeHmacSha256(fmt.Sprintf("%s.%s", parts[0], parts[1]), secret)
+	if !hmac.Equal([]byte(sig), []byte(parts[2])) {
+		return fmt.Errorf("invalid token signature")
+	}
+	header, err := Base64URL2Bytes(parts[0])
+	if err != nil {
+		return fmt.Errorf("invalid token header:%w", err)
+	}
+	headers := make(map[string]string)
+	if err := json.Unmarshal(header, &headers); err != nil || headers["alg"] != "HS256" {
+		return fmt.Errorf("unsupported token header %s", header)
+	}
+	payload, err := Base64URL2Bytes(parts[1])
+	if err != nil {
+		return fmt.Errorf("invalid token payload:%w", err)
+	}
+	exp := struct {
+		Exp int64 `json:"exp"`
+	}{}
+	if err := json.Unmarshal(payload, &exp); err != nil {
+		return fmt.Errorf("invalid token payload:%w", err)
+	}
+	if exp.Exp > 0 && time.Now().Unix() > exp.Exp {
+		return fmt.Errorf("token expired")
+	}
+	return json.Unmarshal(payload, claims)
+}
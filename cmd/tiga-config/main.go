@@ -0,0 +1,245 @@
+// tiga-config 配置相关的命令行工具
+//
+//	tiga-config encrypt [-prefix TIGA] <value>            加密配置值，输出 enc:AES:<hex>
+//	tiga-config decrypt [-prefix TIGA] <value>            解密 enc:AES:<hex>
+//	tiga-config dump -dir <dir> [-env dev,prod]           输出每个环境最终生效的配置，敏感配置会被隐藏
+//	tiga-config diff -dir <dir> <env> <env>               逐个key对比两个环境的配置
+//	tiga-config check -dir <dir> -schema <file> [-env ..] 按声明校验配置，不通过时以非0状态码退出
+//
+// 主密钥通过环境变量 <prefix>_MASTER_KEY 传入
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spark-lence/tiga"
+)
+
+// commandNames 命令的展示顺序
+var commandNames = []string{"encrypt", "decrypt", "dump", "diff", "check"}
+
+var usages = map[string]string{
+	"encrypt": "encrypt [-prefix TIGA] <value>",
+	"decrypt": "decrypt [-prefix TIGA] <value>",
+	"dump":    "dump -dir <dir> [-env dev,prod]",
+	"diff":    "diff -dir <dir> <env> <env>",
+	"check":   "check -dir <dir> -schema <file> [-env dev,prod]",
+}
+
+var commands = map[string]func(args []string) error{
+	"encrypt": runEncrypt,
+	"decrypt": runDecrypt,
+	"dump":    runDump,
+	"diff":    runDiff,
+	"check":   runCheck,
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: tiga-config <command> [arguments]")
+	for _, name := range commandNames {
+		fmt.Fprintf(os.Stderr, "  tiga-config %s\n", usages[name])
+	}
+}
+
+func usageError(name string) error {
+	return fmt.Errorf("usage: tiga-config %s", usages[name])
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	run, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+	if err := run(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// masterKey 从环境变量读取主密钥
+func masterKey(prefix string) ([]byte, error) {
+	key := os.Getenv(tiga.MasterKeyEnv(prefix))
+	if key == "" {
+		return nil, fmt.Errorf("master key %s is not set", tiga.MasterKeyEnv(prefix))
+	}
+	return []byte(key), nil
+}
+
+func parseSecretArgs(name string, args []string) (string, string, error) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	prefix := fs.String("prefix", tiga.DefaultEnvPrefix, "environment variable prefix")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		return "", "", usageError(name)
+	}
+	return *prefix, fs.Arg(0), nil
+}
+
+func runEncrypt(args []string) error {
+	prefix, value, err := parseSecretArgs("encrypt", args)
+	if err != nil {
+		return err
+	}
+	key, err := masterKey(prefix)
+	if err != nil {
+		return err
+	}
+	secret, err := tiga.EncryptSecret(key, value)
+	if err != nil {
+		return err
+	}
+	fmt.Println(secret)
+	return nil
+}
+
+func runDecrypt(args []string) error {
+	prefix, value, err := parseSecretArgs("decrypt", args)
+	if err != nil {
+		return err
+	}
+	key, err := masterKey(prefix)
+	if err != nil {
+		return err
+	}
+	plaintext, err := tiga.DecryptSecret(key, value)
+	if err != nil {
+		return err
+	}
+	fmt.Println(plaintext)
+	return nil
+}
+
+// loadEnvs 加载指定的环境，未指定时加载配置中出现的所有环境
+func loadEnvs(dir string, envList string) (map[string]*tiga.Configuration, []string, error) {
+	envs := make([]string, 0)
+	for _, env := range strings.Split(envList, ",") {
+		if env = strings.TrimSpace(env); env != "" {
+			envs = append(envs, env)
+		}
+	}
+	if len(envs) == 0 {
+		config, err := tiga.LoadSettings("", dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		envs = config.Envs()
+	}
+	configs := make(map[string]*tiga.Configuration, len(envs))
+	for _, env := range envs {
+		config, err := tiga.LoadSettings(env, dir)
+		if err != nil {
+			return nil, nil, fmt.Errorf("load settings of %s failed:%w", env, err)
+		}
+		configs[env] = config
+	}
+	return configs, envs, nil
+}
+
+func sortedKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func runDump(args []string) error {
+	fs := flag.NewFlagSet("dump", flag.ExitOnError)
+	dir := fs.String("dir", ".", "settings directory")
+	envList := fs.String("env", "", "comma separated environments, default all")
+	_ = fs.Parse(args)
+	configs, envs, err := loadEnvs(*dir, *envList)
+	if err != nil {
+		return err
+	}
+	for i, env := range envs {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("[%s]\n", env)
+		values := tiga.MaskSecrets(configs[env].Effective())
+		for _, key := range sortedKeys(values) {
+			fmt.Printf("%s = %v\n", key, values[key])
+		}
+	}
+	return nil
+}
+
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	dir := fs.String("dir", ".", "settings directory")
+	_ = fs.Parse(args)
+	if fs.NArg() != 2 {
+		return usageError("diff")
+	}
+	configs, envs, err := loadEnvs(*dir, strings.Join(fs.Args(), ","))
+	if err != nil {
+		return err
+	}
+	left := tiga.MaskSecrets(configs[envs[0]].Effective())
+	right := tiga.MaskSecrets(configs[envs[1]].Effective())
+	fmt.Printf("--- %s\n+++ %s\n", envs[0], envs[1])
+	for _, change := range tiga.DiffSettings(left, right) {
+		_, inLeft := left[change.Key]
+		_, inRight := right[change.Key]
+		switch {
+		case !inRight:
+			fmt.Printf("- %s = %v\n", change.Key, change.Old)
+		case !inLeft:
+			fmt.Printf("+ %s = %v\n", change.Key, change.New)
+		default:
+			fmt.Printf("~ %s: %v => %v\n", change.Key, change.Old, change.New)
+		}
+	}
+	return nil
+}
+
+func runCheck(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	dir := fs.String("dir", ".", "settings directory")
+	schemaPath := fs.String("schema", "", "schema file")
+	envList := fs.String("env", "", "comma separated environments, default all")
+	_ = fs.Parse(args)
+	if *schemaPath == "" {
+		return usageError("check")
+	}
+	schema, err := tiga.LoadSettingsSchema(*schemaPath)
+	if err != nil {
+		return err
+	}
+	configs, envs, err := loadEnvs(*dir, *envList)
+	if err != nil {
+		return err
+	}
+	failed := false
+	for _, env := range envs {
+		err := configs[env].CheckSchema(schema)
+		var schemaErr *tiga.SchemaError
+		if errors.As(err, &schemaErr) {
+			failed = true
+			for _, field := range schemaErr.Fields {
+				fmt.Printf("[%s] %s %s\n", env, field.Key, field.Reason)
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("[%s] ok\n", env)
+	}
+	if failed {
+		return fmt.Errorf("settings do not match schema %s", *schemaPath)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+// tiga-migrate 执行目录中的SQL迁移
+//
+//	tiga-migrate [-dir <dir>] [-env dev] -migrations <dir> up [-to <version>] [-dry-run]   执行迁移
+//	tiga-migrate [-dir <dir>] [-env dev] -migrations <dir> down [-steps 1] [-dry-run]      回滚最近的迁移
+//	tiga-migrate [-dir <dir>] [-env dev] -migrations <dir> status                          查看迁移状态
+//
+// 数据库连接使用配置中的 mysql 配置，迁移文件的格式为 <版本号>_<名称>.up.sql 与 <版本号>_<名称>.down.sql，
+// 包含Go步骤的迁移需要在业务程序中注册后通过 Migrator.Run 执行
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/spark-lence/tiga"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: tiga-migrate [-dir <dir>] [-env <env>] -migrations <dir> <up|down|status> [arguments]")
+	fmt.Fprintln(os.Stderr, "  up [-to <version>] [-dry-run]")
+	fmt.Fprintln(os.Stderr, "  down [-steps 1] [-dry-run]")
+	fmt.Fprintln(os.Stderr, "  status")
+}
+
+func main() {
+	fs := flag.NewFlagSet("tiga-migrate", flag.ExitOnError)
+	fs.Usage = usage
+	dir := fs.String("dir", ".", "settings directory")
+	env := fs.String("env", "", "settings environment")
+	migrations := fs.String("migrations", "migrations", "SQL migrations directory")
+	_ = fs.Parse(os.Args[1:])
+	if fs.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	if err := run(*dir, *env, *migrations, fs.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(dir, env, migrations string, args []string) error {
+	config, err := tiga.LoadSettings(env, dir)
+	if err != nil {
+		return err
+	}
+	dao := tiga.NewMySQLDao(config)
+	defer dao.Close()
+	migrator := tiga.NewMigrator(dao)
+	if err := migrator.LoadDir(migrations); err != nil {
+		return err
+	}
+	return migrator.Run(context.Background(), args, os.Stdout)
+}
@@ -0,0 +1,274 @@
+package tiga
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidCursor 游标被篡改、已过期的密钥签发或与当前排序字段不匹配
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+var (
+	cursorMu     sync.RWMutex
+	cursorSecret = randomCursorSecret()
+)
+
+// randomCursorSecret 未调用 SetCursorSecret 时使用进程内的随机密钥，游标只在当前进程内有效
+func randomCursorSecret() string {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// SetCursorSecret 设置签名游标的密钥，多实例部署时各实例需要使用相同的密钥
+func SetCursorSecret(secret string) {
+	cursorMu.Lock()
+	defer cursorMu.Unlock()
+	cursorSecret = secret
+}
+
+func getCursorSecret() string {
+	cursorMu.RLock()
+	defer cursorMu.RUnlock()
+	return cursorSecret
+}
+
+// CursorPagination 基于排序字段(keyset)的分页，翻页时不使用 OFFSET，
+// 并发写入时不会重复或遗漏数据
+type CursorPagination struct {
+	// Cursor 上一页返回的 NextCursor，为空时查询第一页
+	Cursor   string
+	PageSize int32
+	// Keys 排序字段，组合后必须唯一，例如 []string{"id"} 或 []string{"created_at", "id"}，
+	// 为空时 MySQL 使用 id，MongoDB 使用 _id。排序字段的值不能为 NULL
+	Keys []string
+	// Desc 为 true 时按 Keys 降序
+	Desc   bool
+	Query  interface{}
+	Args   []interface{}
+	Select []string
+}
+
+// CursorPage 游标分页的结果，HasMore 为 false 时 NextCursor 为空
+type CursorPage struct {
+	NextCursor string `json:"next_cursor"`
+	HasMore    bool   `json:"has_more"`
+}
+
+type cursorPayload struct {
+	Keys   string `bson:"k"`
+	Desc   bool   `bson:"d"`
+	Values bson.A `bson:"v"`
+}
+
+func (c *CursorPagination) keys(defaultKey string) []string {
+	if len(c.Keys) == 0 {
+		return []string{defaultKey}
+	}
+	return c.Keys
+}
+
+// encode 使用排序字段的值生成游标，格式为 base64(bson).hmac
+func (c *CursorPagination) encode(keys []string, values []interface{}) (string, error) {
+	data, err := bson.Marshal(&cursorPayload{Keys: strings.Join(keys, ","), Desc: c.Desc, Values: values})
+	if err != nil {
+		return "", fmt.Errorf("encode cursor failed:%w", err)
+	}
+	payload := Bytes2BaseURL64(data)
+	return fmt.Sprintf("%s.%s", payload, ComputeHmacSha256(payload, getCursorSecret())), nil
+}
+
+// decode 校验游标签名并返回排序字段的值，游标为空时返回 nil
+func (c *CursorPagination) decode(keys []string) ([]interface{}, error) {
+	if c.Cursor == "" {
+		return nil, nil
+	}
+	payload, sig, ok := strings.Cut(c.Cursor, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(ComputeHmacSha256(payload, getCursorSecret()))) {
+		return nil, ErrInvalidCursor
+	}
+	data, err := Base64URL2Bytes(payload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	cursor := &cursorPayload{}
+	if err := bson.Unmarshal(data, cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Keys != strings.Join(keys, ",") || cursor.Desc != c.Desc || len(cursor.Values) != len(keys) {
+		return nil, fmt.Errorf("%w: cursor is ordered by %s but the query is ordered by %s", ErrInvalidCursor, cursor.Keys, strings.Join(keys, ","))
+	}
+	return cursor.Values, nil
+}
+
+func (c *CursorPagination) limit() int {
+	if c.PageSize <= 0 {
+		return 10
+	}
+	return int(c.PageSize)
+}
+
+// Apply 在 db 上添加游标条件、排序与 limit，查询的结果交给 Next 生成下一页的游标。
+// 游标无效时返回 ErrInvalidCursor
+func (c *CursorPagination) Apply(db *gorm.DB) (*gorm.DB, error) {
+	keys := c.keys("id")
+	values, err := c.decode(keys)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.Select) > 0 {
+		db = db.Select(c.Select)
+	}
+	if c.Query != nil {
+		db = db.Where(c.Query, c.Args...)
+	}
+	if values != nil {
+		db = db.Where(clause.Or(c.sqlKeyset(keys, values)...))
+	}
+	for _, key := range keys {
+		db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: key}, Desc: c.Desc})
+	}
+	// 多查一条用于判断是否还有下一页
+	return db.Limit(c.limit() + 1), nil
+}
+
+// sqlKeyset 生成 (a > ?) OR (a = ? AND b > ?) ...，比元组比较更容易命中索引
+func (c *CursorPagination) sqlKeyset(keys []string, values []interface{}) []clause.Expression {
+	exprs := make([]clause.Expression, 0, len(keys))
+	for i := range keys {
+		conds := make([]clause.Expression, 0, i+1)
+		for j := 0; j < i; j++ {
+			conds = append(conds, clause.Eq{Column: clause.Column{Name: keys[j]}, Value: sqlCursorValue(values[j])})
+		}
+		column := clause.Column{Name: keys[i]}
+		if c.Desc {
+			conds = append(conds, clause.Lt{Column: column, Value: sqlCursorValue(values[i])})
+		} else {
+			conds = append(conds, clause.Gt{Column: column, Value: sqlCursorValue(values[i])})
+		}
+		exprs = append(exprs, clause.And(conds...))
+	}
+	return exprs
+}
+
+// cursorTimeKey 游标中的时间编码为 {t: RFC3339Nano}，保留完整精度与时区，
+// bson 的 DateTime 只精确到毫秒，微秒精度的列会在下一页重复返回最后一条记录
+const cursorTimeKey = "t"
+
+// sqlCursorValue 游标中的时间还原为 time.Time
+func sqlCursorValue(val interface{}) interface{} {
+	switch v := val.(type) {
+	case primitive.D:
+		if len(v) == 1 && v[0].Key == cursorTimeKey {
+			if text, ok := v[0].Value.(string); ok {
+				if t, err := time.Parse(time.RFC3339Nano, text); err == nil {
+					return t
+				}
+			}
+		}
+	case primitive.DateTime:
+		// 兼容之前生成的毫秒精度游标
+		return v.Time()
+	}
+	return val
+}
+
+// Next 根据 Apply 后执行查询的结果生成分页信息，models 为查询时传入的切片指针，
+// 多查的一条会从 models 中移除
+func (c *CursorPagination) Next(db *gorm.DB, models interface{}) (*CursorPage, error) {
+	if db.Error != nil {
+		return nil, db.Error
+	}
+	rows := reflect.ValueOf(models)
+	if rows.Kind() != reflect.Ptr || rows.Elem().Kind() != reflect.Slice {
+		return nil, fmt.Errorf("models must be a pointer to slice, got %T", models)
+	}
+	rows = rows.Elem()
+	page := &CursorPage{}
+	if rows.Len() <= c.limit() {
+		return page, nil
+	}
+	rows.SetLen(c.limit())
+	if db.Statement.Schema == nil {
+		return nil, fmt.Errorf("unknown schema of %T", models)
+	}
+	last := reflect.Indirect(rows.Index(rows.Len() - 1))
+	keys := c.keys("id")
+	values := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		field := db.Statement.Schema.LookUpField(key)
+		if field == nil {
+			return nil, fmt.Errorf("cursor key %s not found in %s", key, db.Statement.Schema.Name)
+		}
+		val, _ := field.ValueOf(db.Statement.Context, last)
+		values = append(values, cursorValue(val))
+	}
+	cursor, err := c.encode(keys, values)
+	if err != nil {
+		return nil, err
+	}
+	page.NextCursor = cursor
+	page.HasMore = true
+	return page, nil
+}
+
+// cursorValue 将排序字段的值转换为可以写入bson的类型
+func cursorValue(val interface{}) interface{} {
+	switch v := val.(type) {
+	case time.Time:
+		return bson.D{{Key: cursorTimeKey, Value: v.Format(time.RFC3339Nano)}}
+	case *time.Time:
+		if v != nil {
+			return bson.D{{Key: cursorTimeKey, Value: v.Format(time.RFC3339Nano)}}
+		}
+	case uint:
+		return int64(v)
+	case uint64:
+		return int64(v)
+	}
+	return val
+}
+
+// mongoKeyset 生成 MongoDB 的游标条件
+func (c *CursorPagination) mongoKeyset(keys []string, values []interface{}) bson.M {
+	op := "$gt"
+	if c.Desc {
+		op = "$lt"
+	}
+	or := make(bson.A, 0, len(keys))
+	for i := range keys {
+		cond := bson.D{}
+		for j := 0; j < i; j++ {
+			cond = append(cond, bson.E{Key: keys[j], Value: values[j]})
+		}
+		cond = append(cond, bson.E{Key: keys[i], Value: bson.M{op: values[i]}})
+		or = append(or, cond)
+	}
+	return bson.M{"$or": or}
+}
+
+func (c *CursorPagination) mongoSort(keys []string) bson.D {
+	order := 1
+	if c.Desc {
+		order = -1
+	}
+	sort := bson.D{}
+	for _, key := range keys {
+		sort = append(sort, bson.E{Key: key, Value: order})
+	}
+	return sort
+}
@@ -0,0 +1,158 @@
+package tiga
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+const (
+	DialectMySQL    = "mysql"
+	DialectPostgres = "postgres"
+	DialectSQLite   = "sqlite"
+)
+
+// Dialect 数据库方言，负责生成连接与创建库，通过 mysql.dialect 配置选择，
+// 内置 mysql、postgres 与 sqlite(纯Go实现，不需要cgo)，其他数据库可以通过 RegisterDialect 注册
+type Dialect interface {
+	// Dialector 返回连接 addr(host:port) 上 opts.Database 的 gorm.Dialector，
+	// version 不为空时为主库的版本，用于连接副本时跳过版本查询
+	Dialector(opts *MySQLOptions, addr string, version string) gorm.Dialector
+	// CreateDatabase 库不存在时创建
+	CreateDatabase(opts *MySQLOptions) error
+	// DefaultPort 未配置 mysql.port 时使用的端口，为0表示嵌入式数据库，不需要 host 与 user
+	DefaultPort() int
+}
+
+// poolConfigurer 连接后需要调整连接池的方言
+type poolConfigurer interface {
+	configurePool(db *sql.DB, opts *MySQLOptions)
+}
+
+var (
+	dialectsMu sync.RWMutex
+	dialects   = map[string]Dialect{
+		DialectMySQL:    mysqlDialect{},
+		DialectPostgres: postgresDialect{},
+		DialectSQLite:   sqliteDialect{},
+	}
+)
+
+// RegisterDialect 注册数据库方言，name 与已有方言相同时覆盖
+func RegisterDialect(name string, dialect Dialect) {
+	dialectsMu.Lock()
+	defer dialectsMu.Unlock()
+	dialects[name] = dialect
+}
+
+func getDialect(name string) (Dialect, bool) {
+	dialectsMu.RLock()
+	defer dialectsMu.RUnlock()
+	dialect, ok := dialects[name]
+	return dialect, ok
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Dialector(opts *MySQLOptions, addr string, version string) gorm.Dialector {
+	dsn := fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8mb4&parseTime=True&loc=Local", opts.User, opts.Password, addr, opts.Database)
+	return mysql.New(mysql.Config{
+		DSN:                       dsn,           // DSN
+		DefaultStringSize:         256,           // string 类型字段的默认长度
+		DisableDatetimePrecision:  true,          // 禁用 datetime 精度，MySQL 5.6 之前的数据库不支持
+		DontSupportRenameIndex:    true,          // 重命名索引时采用删除并新建的方式，MySQL 5.7 之前的数据库和 MariaDB 不支持重命名索引
+		DontSupportRenameColumn:   true,          // 用 `change` 重命名列，MySQL 8 之前的数据库和 MariaDB 不支持重命名列
+		SkipInitializeWithVersion: version != "", // 根据当前 MySQL 版本自动配置，副本使用主库的版本
+		ServerVersion:             version,
+	})
+}
+
+func (mysqlDialect) CreateDatabase(opts *MySQLOptions) error {
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/", opts.User, opts.Password, opts.Host, opts.Port)
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return fmt.Errorf("Failed to connect to database server: %w", err)
+	}
+	defer db.Close()
+	_, err = db.Exec(fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`", opts.Database))
+	if err != nil {
+		return fmt.Errorf("Failed to create database: %w", err)
+	}
+	return nil
+}
+
+func (mysqlDialect) DefaultPort() int {
+	return 3306
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) dsn(opts *MySQLOptions, addr string, database string) string {
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(opts.User, opts.Password),
+		Host:     addr,
+		Path:     "/" + database,
+		RawQuery: url.Values{"sslmode": []string{opts.SSLMode}}.Encode(),
+	}
+	return u.String()
+}
+
+func (d postgresDialect) Dialector(opts *MySQLOptions, addr string, version string) gorm.Dialector {
+	return postgres.New(postgres.Config{DSN: d.dsn(opts, addr, opts.Database)})
+}
+
+// CreateDatabase PostgreSQL 不支持 CREATE DATABASE IF NOT EXISTS，先在 postgres 库中查询是否存在
+func (d postgresDialect) CreateDatabase(opts *MySQLOptions) error {
+	db, err := sql.Open("pgx", d.dsn(opts, fmt.Sprintf("%s:%d", opts.Host, opts.Port), "postgres"))
+	if err != nil {
+		return fmt.Errorf("Failed to connect to database server: %w", err)
+	}
+	defer db.Close()
+	var exists bool
+	if err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)", opts.Database).Scan(&exists); err != nil {
+		return fmt.Errorf("Failed to query database: %w", err)
+	}
+	if exists {
+		return nil
+	}
+	_, err = db.Exec(fmt.Sprintf(`CREATE DATABASE "%s"`, strings.ReplaceAll(opts.Database, `"`, `""`)))
+	if err != nil {
+		return fmt.Errorf("Failed to create database: %w", err)
+	}
+	return nil
+}
+
+func (postgresDialect) DefaultPort() int {
+	return 5432
+}
+
+// sqliteDialect mysql.database 为数据库文件路径，:memory: 为内存数据库
+type sqliteDialect struct{}
+
+func (sqliteDialect) Dialector(opts *MySQLOptions, addr string, version string) gorm.Dialector {
+	return sqlite.Open(opts.Database)
+}
+
+func (sqliteDialect) CreateDatabase(opts *MySQLOptions) error {
+	// 数据库文件在连接时自动创建
+	return nil
+}
+
+func (sqliteDialect) DefaultPort() int {
+	return 0
+}
+
+// configurePool 内存数据库的每个连接都是独立的库，只使用一个连接
+func (sqliteDialect) configurePool(db *sql.DB, opts *MySQLOptions) {
+	if strings.Contains(opts.Database, ":memory:") || strings.Contains(opts.Database, "mode=memory") {
+		db.SetMaxOpenConns(1)
+	}
+}
@@ -0,0 +1,155 @@
+package tiga
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type memoryTestUser struct {
+	ID    uint
+	Email string `gorm:"uniqueIndex"`
+	Name  string
+}
+
+func newMemoryTestDao(t *testing.T) *MySQLDao {
+	t.Helper()
+	dao := NewSQLiteMemoryDao()
+	t.Cleanup(func() { _ = dao.Close() })
+	if err := dao.AutoMigrate(&memoryTestUser{}); err != nil {
+		t.Fatalf("migrate failed:%v", err)
+	}
+	return dao
+}
+
+func TestSQLiteMemoryDaoCreateAndFind(t *testing.T) {
+	dao := newMemoryTestDao(t)
+	ctx := context.Background()
+	if err := dao.Create(ctx, &memoryTestUser{Email: "a@x.com", Name: "a"}, nil); err != nil {
+		t.Fatalf("create failed:%v", err)
+	}
+	users := make([]*memoryTestUser, 0)
+	if err := dao.Find(ctx, &users, "email = ?", "a@x.com"); err != nil {
+		t.Fatalf("find failed:%v", err)
+	}
+	if len(users) != 1 || users[0].Name != "a" {
+		t.Fatalf("unexpected users %+v", users)
+	}
+}
+
+func TestSQLiteMemoryDaoUpsertWith(t *testing.T) {
+	dao := newMemoryTestDao(t)
+	ctx := context.Background()
+	result, err := dao.UpsertWith(ctx, []*memoryTestUser{{Email: "a@x.com", Name: "a"}, {Email: "b@x.com", Name: "b"}}, UpsertOnConflict("email"))
+	if err != nil {
+		t.Fatalf("upsert failed:%v", err)
+	}
+	if result.Inserted != 2 {
+		t.Fatalf("expected 2 inserted, got %+v", result)
+	}
+	result, err = dao.UpsertWith(ctx, []*memoryTestUser{
+		{Email: "a@x.com", Name: "a"},
+		{Email: "b@x.com", Name: "b2"},
+		{Email: "c@x.com", Name: "c"},
+	}, UpsertOnConflict("email"))
+	if err != nil {
+		t.Fatalf("upsert failed:%v", err)
+	}
+	if *result != (UpsertResult{Inserted: 1, Updated: 1, Unchanged: 1}) {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	count, err := dao.CountContext(ctx, &memoryTestUser{}, "name = ?", "b2")
+	if err != nil || count != 1 {
+		t.Fatalf("expected updated row, got %d:%v", count, err)
+	}
+}
+
+func TestSQLiteMemoryDaoTransaction(t *testing.T) {
+	dao := newMemoryTestDao(t)
+	ctx := context.Background()
+	errRollback := errors.New("rollback")
+	err := dao.Transaction(ctx, func(ctx context.Context) error {
+		if err := dao.Create(ctx, &memoryTestUser{Email: "a@x.com"}, nil); err != nil {
+			return err
+		}
+		// 内层失败只回滚到 savepoint
+		_ = dao.Transaction(ctx, func(ctx context.Context) error {
+			if err := dao.Create(ctx, &memoryTestUser{Email: "b@x.com"}, nil); err != nil {
+				return err
+			}
+			return errRollback
+		})
+		count, err := dao.CountContext(ctx, &memoryTestUser{}, "")
+		if err != nil {
+			return err
+		}
+		if count != 1 {
+			t.Errorf("expected 1 row inside transaction, got %d", count)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("transaction failed:%v", err)
+	}
+	err = dao.Transaction(ctx, func(ctx context.Context) error {
+		if err := dao.Create(ctx, &memoryTestUser{Email: "c@x.com"}, nil); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("expected rollback error, got %v", err)
+	}
+	count, err := dao.CountContext(ctx, &memoryTestUser{}, "")
+	if err != nil || count != 1 {
+		t.Fatalf("expected 1 committed row, got %d:%v", count, err)
+	}
+}
+
+// TestSQLiteMemoryDaoScan 内存数据库只有一个连接，Iterate 占用连接期间不能执行其他查询，
+// Chunk 在每批读取完成后才回调，可以在 fn 中查询
+func TestSQLiteMemoryDaoScan(t *testing.T) {
+	dao := newMemoryTestDao(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	users := make([]*memoryTestUser, 0, 25)
+	for i := 0; i < 25; i++ {
+		users = append(users, &memoryTestUser{Email: string(rune('a'+i)) + "@x.com"})
+	}
+	if err := dao.CreateInBatchesContext(ctx, users); err != nil {
+		t.Fatalf("create failed:%v", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		rows := 0
+		err := dao.Iterate(ctx, &memoryTestUser{}, nil, func(row interface{}) error {
+			rows++
+			return nil
+		})
+		if err == nil && rows != 25 {
+			err = errors.New("iterate missed rows")
+		}
+		if err != nil {
+			done <- err
+			return
+		}
+		var progress ScanProgress
+		err = dao.Chunk(ctx, &memoryTestUser{}, nil, func(ctx context.Context, chunk interface{}) error {
+			_, err := dao.CountContext(ctx, &memoryTestUser{}, "")
+			return err
+		}, ScanChunkSize(10), ScanParallelism(2), ScanProgressFunc(func(p ScanProgress) { progress = p }))
+		if err == nil && (progress.Rows != 25 || progress.Chunks != 3) {
+			err = errors.New("chunk missed rows")
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("scan failed:%v", err)
+		}
+	case <-ctx.Done():
+		t.Fatal("scan deadlocked on the single sqlite connection")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"reflect"
+	"time"
 
 	"go.etcd.io/etcd/api/v3/mvccpb"
 	clientv3 "go.etcd.io/etcd/client/v3"
@@ -41,7 +42,8 @@ func NewEtcdMockDao() *EtcdDao {
 		panic(err)
 	}
 	cli, err := clientv3.New(clientv3.Config{
-		Endpoints: []string{"localhost:0"},
+		Endpoints:   []string{servers.Servers[0].Address},
+		DialTimeout: time.Second,
 	})
 	if err != nil {
 		panic(err)
@@ -130,6 +132,10 @@ func (e EtcdDao) BatchDelete(ctx context.Context, keys []string) (bool, error) {
 	// return e.BatchPut(ctx, ops)
 	return false, nil
 }
+// GetResponse 返回完整的响应，包含响应头中的版本号
+func (e EtcdDao) GetResponse(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	return e.client.Get(ctx, key, opts...)
+}
 func (e EtcdDao) GetWithPrefix(ctx context.Context, prefix string) ([]*mvccpb.KeyValue, error) {
 	rsp, err := e.client.Get(ctx, prefix, clientv3.WithPrefix())
 	if err != nil {
@@ -16,18 +16,32 @@ type InfluxdbDao struct {
 	config *Configuration
 }
 
+// InfluxdbOptions influxdb 连接配置，对应 settings.yaml 中的 influxdb 节点
+type InfluxdbOptions struct {
+	Host   string `config:"host" validate:"required"`
+	Port   int    `config:"port" default:"8086" validate:"min=1,max=65535"`
+	Bucket string `config:"bucket" validate:"required"`
+	Token  string `config:"token" validate:"required"`
+	Org    string `config:"org" validate:"required"`
+}
+
+func NewInfluxdbOptions(config *Configuration) (*InfluxdbOptions, error) {
+	opts := &InfluxdbOptions{}
+	if err := config.Bind("influxdb", opts); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
 func NewInfluxdbDao(config *Configuration) *InfluxdbDao {
-	env := config.GetEnv()
-	host := config.GetConfigByEnv(env, "influxdb.host")
-	port := config.GetConfigByEnv(env, "influxdb.port")
-	bucket := config.GetConfigByEnv(env, "influxdb.bucket").(string)
-	token := config.GetConfigByEnv(env, "influxdb.token").(string)
-	org := config.GetConfigByEnv(env, "influxdb.org").(string)
-	client := influxdb2.NewClientWithOptions(fmt.Sprintf("http://%s:%d", host, port), token, influxdb2.DefaultOptions().SetUseGZip(true).SetMaxRetries(3))
+	opts, err := NewInfluxdbOptions(config)
+	if err != nil {
+		panic(err)
+	}
+	client := influxdb2.NewClientWithOptions(fmt.Sprintf("http://%s:%d", opts.Host, opts.Port), opts.Token, influxdb2.DefaultOptions().SetUseGZip(true).SetMaxRetries(3))
 	return &InfluxdbDao{
 		client: client,
-		bucket: bucket,
-		org:    org,
+		bucket: opts.Bucket,
+		org:    opts.Org,
 		config: config,
 	}
 }
@@ -17,12 +17,28 @@ type KafkaDao struct {
 	partitions map[string][]int
 }
 
+// KafkaOptions kafka 连接配置，对应 settings.yaml 中的 kafka 节点
+type KafkaOptions struct {
+	Addr string `config:"addr" validate:"required"`
+	// Timeout 超时时间，单位秒
+	Timeout int `config:"timeout" default:"10" validate:"min=1"`
+}
+
+func NewKafkaOptions(config *Configuration) (*KafkaOptions, error) {
+	opts := &KafkaOptions{}
+	if err := config.Bind("kafka", opts); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
 func NewKafkaDao(config *Configuration) *KafkaDao {
-	env := config.GetEnv()
-	addr := config.GetConfigByEnv(env, "kafka.addr").(string)
+	opts, err := NewKafkaOptions(config)
+	if err != nil {
+		panic(err)
+	}
 	client := &kafka.Client{
-		Addr:    kafka.TCP(addr),
-		Timeout: 10 * time.Second,
+		Addr:    kafka.TCP(opts.Addr),
+		Timeout: time.Duration(opts.Timeout) * time.Second,
 	}
 	return &KafkaDao{
 		client:     client,
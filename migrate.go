@@ -0,0 +1,516 @@
+package tiga
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Migration 一个版本的迁移，Up/Down 为Go步骤，UpSQL/DownSQL 为SQL步骤，同时设置时先执行SQL
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+	UpSQL   string
+	DownSQL string
+}
+
+func (m *Migration) hasDown() bool {
+	return m.Down != nil || strings.TrimSpace(m.DownSQL) != ""
+}
+
+func (m *Migration) run(tx *gorm.DB, up bool) error {
+	script, step := m.UpSQL, m.Up
+	if !up {
+		script, step = m.DownSQL, m.Down
+	}
+	for _, stmt := range splitSQL(script) {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("exec %s failed:%w", stmt, err)
+		}
+	}
+	if step != nil {
+		return step(tx)
+	}
+	return nil
+}
+
+// schemaMigration schema_migrations 表中已执行的迁移
+type schemaMigration struct {
+	Version   int64     `gorm:"column:version;primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"column:name;size:255"`
+	AppliedAt time.Time `gorm:"column:applied_at"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// MigrationStatus 迁移的执行状态，Missing 表示数据库中已执行但代码中没有注册的版本
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	Missing   bool
+}
+
+// Migrator 按版本号执行迁移并记录在 schema_migrations 表中，
+// 执行期间持有数据库的 advisory lock，同一个库同时只有一个 Migrator 可以执行
+type Migrator struct {
+	dao         *MySQLDao
+	migrations  []*Migration
+	lockTimeout time.Duration
+}
+
+// MigratorOption Migrator 的可选配置
+type MigratorOption func(*Migrator)
+
+// WithLockTimeout 等待其他 Migrator 释放锁的时间，默认30秒，MySQL 的 GET_LOCK 按秒向上取整
+func WithLockTimeout(timeout time.Duration) MigratorOption {
+	return func(m *Migrator) {
+		m.lockTimeout = timeout
+	}
+}
+
+func NewMigrator(dao *MySQLDao, opts ...MigratorOption) *Migrator {
+	m := &Migrator{dao: dao, lockTimeout: 30 * time.Second}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Register 注册迁移，版本号重复时返回错误
+func (m *Migrator) Register(migrations ...*Migration) error {
+	for _, migration := range migrations {
+		if migration.Version <= 0 {
+			return fmt.Errorf("migration %s has invalid version %d", migration.Name, migration.Version)
+		}
+		for _, exist := range m.migrations {
+			if exist.Version == migration.Version {
+				return fmt.Errorf("duplicate migration version %d: %s and %s", migration.Version, exist.Name, migration.Name)
+			}
+		}
+		m.migrations = append(m.migrations, migration)
+	}
+	sort.Slice(m.migrations, func(i, j int) bool {
+		return m.migrations[i].Version < m.migrations[j].Version
+	})
+	return nil
+}
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// LoadDir 加载目录下 <版本号>_<名称>.up.sql 与 <版本号>_<名称>.down.sql 格式的SQL迁移
+func (m *Migrator) LoadDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("read migrations dir %s failed:%w", dir, err)
+	}
+	loaded := make(map[int64]*Migration)
+	for _, entry := range entries {
+		matches := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || matches == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid migration version %s:%w", entry.Name(), err)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		migration, ok := loaded[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: matches[2]}
+			loaded[version] = migration
+		} else if migration.Name != matches[2] {
+			return fmt.Errorf("migration version %d has different names %s and %s", version, migration.Name, matches[2])
+		}
+		if matches[3] == "up" {
+			migration.UpSQL = string(data)
+		} else {
+			migration.DownSQL = string(data)
+		}
+	}
+	migrations := make([]*Migration, 0, len(loaded))
+	for _, migration := range loaded {
+		migrations = append(migrations, migration)
+	}
+	return m.Register(migrations...)
+}
+
+// applied 返回已执行的迁移，schema_migrations 不存在时返回空
+func (m *Migrator) applied(db *gorm.DB) (map[int64]*schemaMigration, error) {
+	records := make([]*schemaMigration, 0)
+	if db.Migrator().HasTable(&schemaMigration{}) {
+		if err := db.Order("version").Find(&records).Error; err != nil {
+			return nil, fmt.Errorf("read schema_migrations failed:%w", err)
+		}
+	}
+	applied := make(map[int64]*schemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// Status 返回所有迁移的执行状态，按版本号排序
+func (m *Migrator) Status(ctx context.Context) ([]*MigrationStatus, error) {
+	applied, err := m.applied(m.dao.db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]*MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := &MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if record, ok := applied[migration.Version]; ok {
+			status.Applied, status.AppliedAt = true, record.AppliedAt
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, record := range applied {
+		statuses = append(statuses, &MigrationStatus{Version: record.Version, Name: record.Name, Applied: true, AppliedAt: record.AppliedAt, Missing: true})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+// Up 按版本号顺序执行未执行的迁移直到 target(含)，target 为0时执行全部。
+// dryRun 不为空时只将SQL输出到 dryRun，不修改数据库
+func (m *Migrator) Up(ctx context.Context, target int64, dryRun io.Writer) ([]*Migration, error) {
+	var done []*Migration
+	err := m.withLock(ctx, dryRun, func(db *gorm.DB) error {
+		applied, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if target > 0 && migration.Version > target {
+				break
+			}
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(db, migration, true, dryRun); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down 按版本号倒序回滚最近执行的 steps 个迁移
+func (m *Migrator) Down(ctx context.Context, steps int, dryRun io.Writer) ([]*Migration, error) {
+	var done []*Migration
+	err := m.withLock(ctx, dryRun, func(db *gorm.DB) error {
+		applied, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if !migration.hasDown() {
+				return fmt.Errorf("migration %d_%s has no down step", migration.Version, migration.Name)
+			}
+			if err := m.apply(db, migration, false, dryRun); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// apply 执行单个迁移并更新 schema_migrations。注意 MySQL 的DDL会隐式提交，
+// 包含DDL的迁移失败时需要手动处理已执行的部分
+func (m *Migrator) apply(db *gorm.DB, migration *Migration, up bool, dryRun io.Writer) error {
+	direction := "up"
+	if !up {
+		direction = "down"
+	}
+	record := func(tx *gorm.DB) error {
+		if up {
+			return tx.Create(&schemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+		}
+		return tx.Delete(&schemaMigration{}, "version = ?", migration.Version).Error
+	}
+	if dryRun != nil {
+		fmt.Fprintf(dryRun, "-- %d_%s %s\n", migration.Version, migration.Name, direction)
+		tx := db.Session(&gorm.Session{DryRun: true, SkipDefaultTransaction: true, Logger: &sqlPrinter{out: dryRun}})
+		if err := migration.run(tx, up); err != nil {
+			return err
+		}
+		return record(tx)
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := migration.run(tx, up); err != nil {
+			return err
+		}
+		return record(tx)
+	})
+	if err != nil {
+		return fmt.Errorf("migrate %d_%s %s failed:%w", migration.Version, migration.Name, direction, err)
+	}
+	GetLogger("migrate").Infof("migrate %d_%s %s", migration.Version, migration.Name, direction)
+	return nil
+}
+
+// withLock 在同一个连接上获取 advisory lock 后执行 fn，dry-run 时不加锁也不创建 schema_migrations
+func (m *Migrator) withLock(ctx context.Context, dryRun io.Writer, fn func(db *gorm.DB) error) error {
+	db := m.dao.db.WithContext(ctx)
+	if dryRun != nil {
+		return fn(db)
+	}
+	// advisory lock 与连接绑定，加锁、迁移与释放锁必须使用同一个连接
+	return db.Connection(func(tx *gorm.DB) error {
+		// Connection 返回的 tx 不是新的会话，需要 NewDB 避免多次查询的条件互相影响
+		conn := tx.Session(&gorm.Session{NewDB: true})
+		lock := fmt.Sprintf("%s.schema_migrations", conn.Migrator().CurrentDatabase())
+		release, err := m.lock(conn, lock)
+		if err != nil {
+			return err
+		}
+		defer release()
+		if !conn.Migrator().HasTable(&schemaMigration{}) {
+			if err := conn.Migrator().CreateTable(&schemaMigration{}); err != nil {
+				return fmt.Errorf("create schema_migrations failed:%w", err)
+			}
+		}
+		return fn(conn)
+	})
+}
+
+// lock 按数据库类型获取名为 name 的 advisory lock，MySQL 使用 GET_LOCK，PostgreSQL 使用 pg_try_advisory_lock，
+// SQLite 同时只有一个写事务，不需要加锁
+func (m *Migrator) lock(conn *gorm.DB, name string) (func(), error) {
+	var acquire func() (bool, error)
+	var unlock string
+	switch conn.Dialector.Name() {
+	case DialectMySQL:
+		acquire = func() (bool, error) {
+			var locked sql.NullInt64
+			err := conn.Raw("SELECT GET_LOCK(?, ?)", name, int(math.Ceil(m.lockTimeout.Seconds()))).Scan(&locked).Error
+			return locked.Valid && locked.Int64 == 1, err
+		}
+		unlock = "SELECT RELEASE_LOCK(?)"
+	case DialectPostgres:
+		// pg_advisory_lock 没有超时参数，使用 try 轮询直到超时
+		acquire = func() (bool, error) {
+			deadline := time.Now().Add(m.lockTimeout)
+			for {
+				var locked bool
+				if err := conn.Raw("SELECT pg_try_advisory_lock(hashtext(?))", name).Scan(&locked).Error; err != nil || locked {
+					return locked, err
+				}
+				if time.Now().After(deadline) {
+					return false, nil
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+		}
+		unlock = "SELECT pg_advisory_unlock(hashtext(?))"
+	default:
+		return func() {}, nil
+	}
+	locked, err := acquire()
+	if err != nil {
+		return nil, fmt.Errorf("acquire migration lock failed:%w", err)
+	}
+	if !locked {
+		return nil, fmt.Errorf("acquire migration lock %s timeout, another migrator is running", name)
+	}
+	return func() {
+		// 使用独立的 context，保证 ctx 取消后仍然释放锁
+		if err := conn.WithContext(context.Background()).Exec(unlock, name).Error; err != nil {
+			GetLogger("migrate").Errorf("release migration lock %s failed:%v", name, err)
+		}
+	}, nil
+}
+
+// Run 执行迁移命令，用于在业务程序中注册Go迁移后提供命令行入口:
+//
+//	up [-to <version>] [-dry-run]   执行迁移
+//	down [-steps 1] [-dry-run]      回滚最近的迁移
+//	status                          查看迁移状态
+func (m *Migrator) Run(ctx context.Context, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("usage: up [-to <version>] [-dry-run] | down [-steps 1] [-dry-run] | status")
+	}
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	fs.SetOutput(out)
+	dry := fs.Bool("dry-run", false, "print the SQL without executing")
+	var dryRun io.Writer
+	switch args[0] {
+	case "up":
+		to := fs.Int64("to", 0, "target version, default latest")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *dry {
+			dryRun = out
+		}
+		done, err := m.Up(ctx, *to, dryRun)
+		printMigrations(out, "applied", done, *dry)
+		return err
+	case "down":
+		steps := fs.Int("steps", 1, "number of migrations to roll back")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *dry {
+			dryRun = out
+		}
+		done, err := m.Down(ctx, *steps, dryRun)
+		printMigrations(out, "rolled back", done, *dry)
+		return err
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Missing {
+				state = "applied (missing)"
+			} else if status.Applied {
+				state = "applied"
+			}
+			appliedAt := ""
+			if status.Applied {
+				appliedAt = status.AppliedAt.Format(time.DateTime)
+			}
+			fmt.Fprintf(out, "%d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %s", args[0])
+	}
+}
+
+func printMigrations(out io.Writer, action string, migrations []*Migration, dry bool) {
+	if dry {
+		return
+	}
+	for _, migration := range migrations {
+		fmt.Fprintf(out, "%s %d_%s\n", action, migration.Version, migration.Name)
+	}
+}
+
+// RenameColumnsMigration 将模型中 old_column 标签声明的列重命名作为一个迁移步骤，
+// Up 按字段顺序将旧列名改为 gorm column 标签中的新列名，Down 按相反的顺序改回旧列名
+func RenameColumnsMigration(version int64, name string, model interface{}) (*Migration, error) {
+	renames, err := columnRenames(model)
+	if err != nil {
+		return nil, err
+	}
+	rename := func(tx *gorm.DB, reverse bool) error {
+		for i := range renames {
+			from, to := renames[i].Old, renames[i].New
+			if reverse {
+				r := renames[len(renames)-1-i]
+				from, to = r.New, r.Old
+			}
+			if err := tx.Migrator().RenameColumn(model, from, to); err != nil {
+				return fmt.Errorf("rename column %s to %s failed:%w", from, to, err)
+			}
+		}
+		return nil
+	}
+	return &Migration{
+		Version: version,
+		Name:    name,
+		Up:      func(tx *gorm.DB) error { return rename(tx, false) },
+		Down:    func(tx *gorm.DB) error { return rename(tx, true) },
+	}, nil
+}
+
+// sqlPrinter dry-run 时输出gorm生成的SQL
+type sqlPrinter struct {
+	out io.Writer
+}
+
+func (p *sqlPrinter) LogMode(logger.LogLevel) logger.Interface      { return p }
+func (p *sqlPrinter) Info(context.Context, string, ...interface{})  {}
+func (p *sqlPrinter) Warn(context.Context, string, ...interface{})  {}
+func (p *sqlPrinter) Error(context.Context, string, ...interface{}) {}
+func (p *sqlPrinter) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	sql, _ := fc()
+	fmt.Fprintf(p.out, "%s;\n", sql)
+}
+
+// splitSQL 按分号拆分SQL脚本，忽略引号与注释中的分号
+func splitSQL(script string) []string {
+	stmts := make([]string, 0)
+	var (
+		buf   strings.Builder
+		quote rune
+	)
+	runes := []rune(script)
+	flush := func() {
+		if stmt := strings.TrimSpace(buf.String()); stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+		buf.Reset()
+	}
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case quote != 0:
+			buf.WriteRune(c)
+			if c == '\\' && quote != '`' && i+1 < len(runes) {
+				i++
+				buf.WriteRune(runes[i])
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+			buf.WriteRune(c)
+		case c == '#' || (c == '-' && i+1 < len(runes) && runes[i+1] == '-'):
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			buf.WriteRune('\n')
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i < len(runes) && !(runes[i] == '*' && i+1 < len(runes) && runes[i+1] == '/') {
+				i++
+			}
+			i++
+			buf.WriteRune(' ')
+		case c == ';':
+			flush()
+		default:
+			buf.WriteRune(c)
+		}
+	}
+	flush()
+	return stmts
+}
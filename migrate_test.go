@@ -0,0 +1,48 @@
+package tiga
+
+import "testing"
+
+type renameTestBefore struct {
+	ID uint
+	A  string `gorm:"column:a"`
+	B  string `gorm:"column:b"`
+}
+
+func (renameTestBefore) TableName() string { return "rename_tests" }
+
+// renameTestAfter b 先改为 c，a 再改为 b，顺序颠倒时两列会冲突
+type renameTestAfter struct {
+	ID uint
+	C  string `gorm:"column:c" old_column:"b"`
+	B  string `gorm:"column:b" old_column:"a"`
+}
+
+func (renameTestAfter) TableName() string { return "rename_tests" }
+
+func TestRenameColumnsMigrationOrder(t *testing.T) {
+	dao := newMemoryTestDao(t)
+	if err := dao.db.AutoMigrate(&renameTestBefore{}); err != nil {
+		t.Fatalf("migrate failed:%v", err)
+	}
+	if err := dao.db.Create(&renameTestBefore{A: "1", B: "2"}).Error; err != nil {
+		t.Fatalf("create failed:%v", err)
+	}
+	migration, err := RenameColumnsMigration(1, "rename", &renameTestAfter{})
+	if err != nil {
+		t.Fatalf("build migration failed:%v", err)
+	}
+	if err := migration.Up(dao.db); err != nil {
+		t.Fatalf("up failed:%v", err)
+	}
+	var after renameTestAfter
+	if err := dao.db.First(&after).Error; err != nil || after.B != "1" || after.C != "2" {
+		t.Fatalf("unexpected row after up %+v:%v", after, err)
+	}
+	if err := migration.Down(dao.db); err != nil {
+		t.Fatalf("down failed:%v", err)
+	}
+	var before renameTestBefore
+	if err := dao.db.First(&before).Error; err != nil || before.A != "1" || before.B != "2" {
+		t.Fatalf("unexpected row after down %+v:%v", before, err)
+	}
+}
@@ -22,16 +22,29 @@ type MongoStruct interface {
 	Read() interface{}
 }
 
-func NewMongodbDao(config *Configuration) *MongodbDao {
-	env := config.GetEnv()
-	username := config.GetConfigByEnv(env, "mongodb.username")
-	password := config.GetConfigByEnv(env, "mongodb.password")
-	host := config.GetConfigByEnv(env, "mongodb.host")
+// MongodbOptions mongodb 连接配置，对应 settings.yaml 中的 mongodb 节点
+type MongodbOptions struct {
+	Username    string `config:"username"`
+	Password    string `config:"password"`
+	Host        string `config:"host" validate:"required"`
+	Port        int    `config:"port" default:"27017" validate:"min=1,max=65535"`
+	DB          string `config:"db" validate:"required"`
+	ConnectSize int    `config:"connectSize" default:"100" validate:"min=1"`
+}
 
-	port := config.GetConfigByEnv(env, "mongodb.port")
-	db := config.GetConfigByEnv(env, "mongodb.db").(string)
-	size := config.GetConfigByEnv(env, "mongodb.connectSize").(int)
-	uri := fmt.Sprintf("mongodb://%s:%s@%s:%d/%s?connect=direct&&authSource=dev", username, password, host, port, db)
+func NewMongodbOptions(config *Configuration) (*MongodbOptions, error) {
+	opts := &MongodbOptions{}
+	if err := config.Bind("mongodb", opts); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+func NewMongodbDao(config *Configuration) *MongodbDao {
+	opts, err := NewMongodbOptions(config)
+	if err != nil {
+		panic(err)
+	}
+	uri := fmt.Sprintf("mongodb://%s:%s@%s:%d/%s?connect=direct&&authSource=dev", opts.Username, opts.Password, opts.Host, opts.Port, opts.DB)
 
 	// 设置连接超时时间
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(10*time.Second))
@@ -39,7 +52,7 @@ func NewMongodbDao(config *Configuration) *MongodbDao {
 	// 通过传进来的uri连接相关的配置
 	o := options.Client().ApplyURI(uri)
 	// 设置最大连接数 - 默认是100 ，不设置就是最大 max 64
-	o.SetMaxPoolSize(uint64(size))
+	o.SetMaxPoolSize(uint64(opts.ConnectSize))
 	// 发起链接
 	client, err := mongo.Connect(ctx, o)
 	if err != nil {
@@ -51,7 +64,7 @@ func NewMongodbDao(config *Configuration) *MongodbDao {
 	}
 	// 返回 client
 	return &MongodbDao{
-		db:     client.Database(db),
+		db:     client.Database(opts.DB),
 		config: config,
 	}
 
@@ -108,6 +121,61 @@ func (m MongodbDao) PagingQuery(collection string, filter interface{}, limit int
 	return data, nil
 }
 
+// CursorQuery 游标分页查询，pagination 中的 Query、Args 与 Select 不生效，
+// 查询条件与返回字段分别使用 filter 与 project
+func (m MongodbDao) CursorQuery(ctx context.Context, collection string, filter interface{}, pagination *CursorPagination, project bson.M) ([]map[string]interface{}, *CursorPage, error) {
+	keys := pagination.keys("_id")
+	values, err := pagination.decode(keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	if filter == nil {
+		filter = bson.M{}
+	}
+	if values != nil {
+		filter = bson.M{"$and": bson.A{filter, pagination.mongoKeyset(keys, values)}}
+	}
+	findoptions := options.Find().SetSort(pagination.mongoSort(keys)).SetLimit(int64(pagination.limit() + 1))
+	if project != nil {
+		findoptions.SetProjection(project)
+	}
+	r, err := m.db.Collection(collection).Find(ctx, filter, findoptions)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer r.Close(ctx)
+	data := make([]map[string]interface{}, 0, pagination.limit()+1)
+	for r.Next(ctx) {
+		var item map[string]interface{}
+		if err := r.Decode(&item); err != nil {
+			return nil, nil, err
+		}
+		data = append(data, item)
+	}
+	if err := r.Err(); err != nil {
+		return nil, nil, err
+	}
+	page := &CursorPage{}
+	if len(data) <= pagination.limit() {
+		return data, page, nil
+	}
+	data = data[:pagination.limit()]
+	last := data[len(data)-1]
+	next := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		val, ok := last[key]
+		if !ok {
+			return nil, nil, fmt.Errorf("cursor key %s not found in %s, it must be included in the projection", key, collection)
+		}
+		next = append(next, val)
+	}
+	if page.NextCursor, err = pagination.encode(keys, next); err != nil {
+		return nil, nil, err
+	}
+	page.HasMore = true
+	return data, page, nil
+}
+
 func (m MongodbDao) AggregateQuery(collection string, pipeline []bson.D) ([]bson.M, error) {
 	// 执行聚合查询
 	c := m.db.Collection(collection)
@@ -8,14 +8,12 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"google.golang.org/protobuf/types/known/timestamppb"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 	"gorm.io/gorm/schema"
 )
@@ -27,6 +25,8 @@ type JSONField struct {
 type MySQLDao struct {
 	db  *gorm.DB
 	cfg *Configuration
+	// replicas 只读副本，为空时读写都使用主库
+	replicas *replicaSet
 }
 type TxProvider func() *gorm.DB
 type Pagination struct {
@@ -52,47 +52,154 @@ func NewMySQLMockDao() (*MySQLDao, sqlmock.Sqlmock) {
 	if err != nil {
 		panic(err)
 	}
+	if err := registerModelPlugins(_DB); err != nil {
+		panic(err)
+	}
 	return &MySQLDao{
 		db: _DB,
 	}, mock
 
 }
-func NewMySQLDao(config *Configuration) *MySQLDao {
-	host := config.GetString("mysql.host")
-	port := config.GetInt("mysql.port")
-	user := config.GetString("mysql.user")
-	prefix := config.GetString("mysql.table_prefix")
-	password := config.GetString("mysql.password")
-	database := config.GetString("mysql.database")
-	err := CreateDatabase(config)
-	if err != nil {
-		panic(err)
+// MySQLOptions 数据库连接配置，对应 settings.yaml 中的 mysql 节点，mysql.dialect 可以选择其他数据库，见 Dialect
+type MySQLOptions struct {
+	// Dialect 数据库类型，mysql、postgres、sqlite 或 RegisterDialect 注册的类型
+	Dialect string `config:"dialect" default:"mysql"`
+	// Host 与 User 在 sqlite 等嵌入式数据库中不需要
+	Host string `config:"host"`
+	// Port 未配置时使用方言的默认端口
+	Port     int    `config:"port" validate:"omitempty,min=1,max=65535"`
+	User     string `config:"user"`
+	Password string `config:"password"`
+	// Database 库名，sqlite 为数据库文件路径，:memory: 为内存数据库
+	Database    string `config:"database" validate:"required"`
+	TablePrefix string `config:"table_prefix"`
+	// SSLMode PostgreSQL 的 sslmode
+	SSLMode string `config:"ssl_mode" default:"disable"`
+	// Replicas 只读副本的地址列表，格式为 host:port，用户名、密码与库名与主库相同
+	Replicas []string `config:"replicas"`
+	// ReplicaPolicy 选择副本的策略，round_robin 轮询，least_latency 选择健康检查延迟最低的副本
+	ReplicaPolicy string `config:"replica_policy" default:"round_robin" validate:"oneof=round_robin least_latency"`
+	// HealthCheckInterval 副本健康检查的间隔，检查失败的副本不再接收读请求，恢复后重新加入
+	HealthCheckInterval time.Duration `config:"health_check_interval" default:"5s"`
+	// Validate 为 true 时写入前按模型的 validate 标签校验，见 ValidatorMySQLPlugin
+	Validate bool `config:"validate"`
+}
+
+func NewMySQLOptions(config *Configuration) (*MySQLOptions, error) {
+	opts := &MySQLOptions{}
+	if err := config.Bind("mysql", opts); err != nil {
+		return nil, err
+	}
+	dialect, ok := getDialect(opts.Dialect)
+	if !ok {
+		return nil, &ConfigBindError{Prefix: "mysql", Fields: []ConfigFieldError{{Key: "mysql.dialect", Reason: fmt.Sprintf("unknown dialect %s", opts.Dialect)}}}
 	}
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local", user, password, host, port, database)
-
-	db, err := gorm.Open(mysql.New(mysql.Config{
-		DSN:                       dsn,   // DSN
-		DefaultStringSize:         256,   // string 类型字段的默认长度
-		DisableDatetimePrecision:  true,  // 禁用 datetime 精度，MySQL 5.6 之前的数据库不支持
-		DontSupportRenameIndex:    true,  // 重命名索引时采用删除并新建的方式，MySQL 5.7 之前的数据库和 MariaDB 不支持重命名索引
-		DontSupportRenameColumn:   true,  // 用 `change` 重命名列，MySQL 8 之前的数据库和 MariaDB 不支持重命名列
-		SkipInitializeWithVersion: false, // 根据当前 MySQL 版本自动配置
+	if opts.Port == 0 {
+		opts.Port = dialect.DefaultPort()
+	}
+	if dialect.DefaultPort() > 0 {
+		bindErr := &ConfigBindError{Prefix: "mysql"}
+		if opts.Host == "" {
+			bindErr.Fields = append(bindErr.Fields, ConfigFieldError{Key: "mysql.host", Reason: "is missing"})
+		}
+		if opts.User == "" {
+			bindErr.Fields = append(bindErr.Fields, ConfigFieldError{Key: "mysql.user", Reason: "is missing"})
+		}
+		if len(bindErr.Fields) > 0 {
+			return nil, bindErr
+		}
+	}
+	return opts, nil
+}
 
-	}), &gorm.Config{
+// openDB 使用 opts.Dialect 连接 addr 上的 opts.Database，version 不为空时跳过版本查询与连接检查，用于连接副本
+func openDB(opts *MySQLOptions, addr string, version string) (*gorm.DB, error) {
+	dialect, ok := getDialect(opts.Dialect)
+	if !ok {
+		return nil, fmt.Errorf("unknown dialect %s", opts.Dialect)
+	}
+	db, err := gorm.Open(dialect.Dialector(opts, addr, version), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent),
 		NamingStrategy: schema.NamingStrategy{
-			TablePrefix: prefix, // 表名前缀，`User` 的表名应该是 `tiga_users`
+			TablePrefix: opts.TablePrefix, // 表名前缀，`User` 的表名应该是 `tiga_users`
 		},
+		DisableAutomaticPing: version != "",
 	})
+	if err != nil {
+		return nil, err
+	}
+	if configurer, ok := dialect.(poolConfigurer); ok {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		configurer.configurePool(sqlDB, opts)
+	}
+	return db, nil
+}
+
+// NewMySQLDao 按 mysql.dialect 连接 mysql.host 上的主库，配置了 mysql.replicas 时读请求分发到健康的副本，见 UsePrimary
+func NewMySQLDao(config *Configuration) *MySQLDao {
+	opts, err := NewMySQLOptions(config)
 	if err != nil {
 		panic(err)
 	}
-	return &MySQLDao{
-		db:  db,
-		cfg: config,
+	dao, err := newDao(opts)
+	if err != nil {
+		panic(err)
+	}
+	dao.cfg = config
+	return dao
+}
+
+// NewSQLiteMemoryDao 基于内存 SQLite 的 MySQLDao，用于测试时代替 NewMySQLMockDao 使用真实的数据库语义。
+// 内存数据库只有一个连接，Iterate 的 fn 中不能执行其他查询
+func NewSQLiteMemoryDao() *MySQLDao {
+	dao, err := newDao(&MySQLOptions{Dialect: DialectSQLite, Database: ":memory:"})
+	if err != nil {
+		panic(err)
+	}
+	return dao
+}
+
+func newDao(opts *MySQLOptions) (*MySQLDao, error) {
+	if err := createDatabase(opts); err != nil {
+		return nil, err
+	}
+	db, err := openDB(opts, fmt.Sprintf("%s:%d", opts.Host, opts.Port), "")
+	if err != nil {
+		return nil, err
+	}
+	if err := registerModelPlugins(db); err != nil {
+		return nil, err
+	}
+	if opts.Validate {
+		if err := db.Use(&ValidatorMySQLPlugin{}); err != nil {
+			return nil, err
+		}
+	}
+	dao := &MySQLDao{
+		db: db,
+	}
+	if len(opts.Replicas) > 0 {
+		version := ""
+		if dialector, ok := db.Dialector.(*mysql.Dialector); ok {
+			version = dialector.ServerVersion
+		}
+		replicas, err := newReplicaSet(opts, func(addr string) (*gorm.DB, error) {
+			return openDB(opts, addr, version)
+		})
+		if err != nil {
+			return nil, err
+		}
+		dao.replicas = replicas
 	}
+	return dao, nil
 }
 func (m MySQLDao) Close() error {
+	if m.replicas != nil {
+		m.replicas.close()
+	}
 	db, err := m.db.DB()
 	if err != nil {
 		return err
@@ -105,28 +212,25 @@ func (m MySQLDao) RegisterTimeSerializer() {
 
 }
 func CreateDatabase(config *Configuration) error {
-
-	host := config.GetString("mysql.host")
-	port := config.GetInt("mysql.port")
-	user := config.GetString("mysql.user")
-	password := config.GetString("mysql.password")
-	database := config.GetString("mysql.database")
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/", user, password, host, port)
-	db, err := sql.Open("mysql", dsn)
+	opts, err := NewMySQLOptions(config)
 	if err != nil {
-		return fmt.Errorf("Failed to connect to database server: %w", err)
+		return err
 	}
-	defer db.Close()
-	_, err = db.Exec(fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`", database))
-	if err != nil {
-		return fmt.Errorf("Failed to create database: %w", err)
-
+	return createDatabase(opts)
+}
+func createDatabase(opts *MySQLOptions) error {
+	dialect, ok := getDialect(opts.Dialect)
+	if !ok {
+		return fmt.Errorf("unknown dialect %s", opts.Dialect)
 	}
-	return nil
-
+	return dialect.CreateDatabase(opts)
 }
 func (m MySQLDao) Save(model interface{}) error {
-	return m.db.Save(model).Error
+	return m.SaveContext(context.Background(), model)
+}
+// SaveContext 与 Save 相同，ctx 中有事务时在事务中执行
+func (m MySQLDao) SaveContext(ctx context.Context, model interface{}) error {
+	return m.conn(ctx, nil).Save(model).Error
 }
 func WithTx(tx *gorm.DB) TxProvider {
 	return func() *gorm.DB {
@@ -134,72 +238,44 @@ func WithTx(tx *gorm.DB) TxProvider {
 	}
 }
 func (m MySQLDao) Create(ctx context.Context, model interface{}, tx *gorm.DB) error {
-	if tx != nil {
-		return tx.WithContext(ctx).Create(model).Error
-
-	}
-	return m.db.WithContext(ctx).Create(model).Error
+	return m.conn(ctx, tx).Create(model).Error
 }
+// Upsert 插入 model，主键冲突时更新 updateSelect 中的列，未指定时更新除主键与创建相关列之外的全部列，
+// 返回是否更新了已存在的记录，需要指定冲突列或批量写入时使用 UpsertWith
 func (m MySQLDao) Upsert(ctx context.Context, model interface{}, tx *gorm.DB, updateSelect ...string) (bool, error) {
-
-	prefix := m.cfg.GetString("mysql.table_prefix")
-
-	s, err := schema.Parse(model, &sync.Map{}, schema.NamingStrategy{TablePrefix: prefix})
-	if err != nil {
-		return false, err
-
-	}
-	structNames := make(map[string]string)
-	for _, field := range s.Fields {
-		structNames[field.StructField.Name] = field.DBName
+	if tx != nil {
+		ctx = ContextWithTx(ctx, tx)
 	}
-	modelType := reflect.TypeOf(model).Elem()
-	fields := make([]string, 0)
+	opts := make([]UpsertOption, 0)
 	if len(updateSelect) > 0 {
-		fields = append(fields, updateSelect...)
-	} else {
-		for i := 0; i < modelType.NumField(); i++ {
-			field := modelType.Field(i).Name
-			primary := modelType.Field(i).Tag.Get("primary")
-			if field != "CreatedAt" && modelType.Field(i).Tag.Get("gorm") != "" && modelType.Field(i).Tag.Get("gorm") != "-" && (primary == "" || primary == "-") { // 确保排除CreatedAt字段
-				fields = append(fields, structNames[field])
-			}
-		}
-	}
-	db := m.db
-	if tx != nil {
-		db = tx
+		opts = append(opts, UpsertUpdateColumns(updateSelect...))
 	}
-	result := db.Clauses(clause.OnConflict{
-		DoUpdates: clause.AssignmentColumns(fields),
-	}).Create(model)
-	if result.Error != nil {
-		return false, result.Error
+	result, err := m.UpsertWith(ctx, model, opts...)
+	if err != nil {
+		return false, err
 	}
-	// log.Printf("result:%+v", result.RowsAffected)
-	return result.RowsAffected > 1, nil
+	return result.Outcome() == UpsertUpdated, nil
 }
+// Update 更新 model，模型有 version 列且版本号不为0时使用乐观锁，记录已被修改时返回 *VersionConflictError
 func (m MySQLDao) Update(ctx context.Context, model interface{}, value interface{}, tx *gorm.DB) error {
-	db := m.db
-	if tx != nil {
-		db = tx
-	}
-	return db.WithContext(ctx).Model(model).Updates(value).Error
+	return WithOptimisticLock(m.conn(ctx, tx)).Model(model).Updates(value).Error
 }
 func (m MySQLDao) UpdateColumns(model interface{}, value interface{}) error {
-	return m.db.Where(model).Updates(value).Error
+	return m.UpdateColumnsContext(context.Background(), model, value)
+}
+// UpdateColumnsContext 与 UpdateColumns 相同，ctx 中有事务时在事务中执行
+func (m MySQLDao) UpdateColumnsContext(ctx context.Context, model interface{}, value interface{}) error {
+	return m.conn(ctx, nil).Where(model).Updates(value).Error
 }
 func (m MySQLDao) UpdateWithQuery(model interface{}, value interface{}, fields []string, query interface{}, args ...interface{}) error {
-	return m.db.Model(model).Where(query, args...).Select(fields).Updates(value).Error
+	return m.UpdateWithQueryContext(context.Background(), model, value, fields, query, args...)
+}
+// UpdateWithQueryContext 与 UpdateWithQuery 相同，ctx 中有事务时在事务中执行
+func (m MySQLDao) UpdateWithQueryContext(ctx context.Context, model interface{}, value interface{}, fields []string, query interface{}, args ...interface{}) error {
+	return m.conn(ctx, nil).Model(model).Where(query, args...).Select(fields).Updates(value).Error
 }
 func (m MySQLDao) UpdateSelectColumns(ctx context.Context, where interface{}, value interface{}, tx *gorm.DB, selectCol ...string) error {
-	var result *gorm.DB
-	if tx != nil {
-		result = tx.WithContext(ctx).Where(where).Select(selectCol).Updates(value)
-
-	} else {
-		result = m.db.WithContext(ctx).Where(where).Select(selectCol).Updates(value)
-	}
+	result := WithOptimisticLock(m.conn(ctx, tx)).Where(where).Select(selectCol).Updates(value)
 	if result.Error!=nil{
 		return fmt.Errorf("update failed:%w",result.Error)
 	}
@@ -209,45 +285,76 @@ func (m MySQLDao) UpdateSelectColumns(ctx context.Context, where interface{}, va
 	return nil
 }
 func (m MySQLDao) Pagination(ctx context.Context, model interface{}, pagination *Pagination) error {
-	base := m.db.WithContext(ctx)
+	base := m.reader(ctx)
 	if len(pagination.Select) > 0 {
 		base = base.Select(pagination.Select)
 	}
 	return base.Where(pagination.Query, pagination.Args...).Limit(int(pagination.PageSize)).Offset(int(pagination.PageSize * (pagination.Page - 1))).Find(model).Error
 }
-func (m MySQLDao) Delete(model interface{}, tx *gorm.DB, conds ...interface{}) error {
-	if tx != nil {
-		return tx.Delete(model, conds...).Error
+// CursorPagination 游标分页查询，model 为切片指针，见 CursorPagination
+func (m MySQLDao) CursorPagination(ctx context.Context, model interface{}, pagination *CursorPagination) (*CursorPage, error) {
+	db, err := pagination.Apply(m.reader(ctx))
+	if err != nil {
+		return nil, err
 	}
-	return m.db.Delete(model, conds...).Error
+	return pagination.Next(db.Find(model), model)
+}
+func (m MySQLDao) Delete(model interface{}, tx *gorm.DB, conds ...interface{}) error {
+	return m.DeleteContext(context.Background(), model, tx, conds...)
+}
+// DeleteContext 与 Delete 相同，tx 为空时使用 ctx 中的事务，ctx 为 Unscoped 时为物理删除
+func (m MySQLDao) DeleteContext(ctx context.Context, model interface{}, tx *gorm.DB, conds ...interface{}) error {
+	return m.conn(ctx, tx).Delete(model, conds...).Error
 }
 func (m MySQLDao) BatchUpdates(models []interface{}, selectCol ...string) error {
-	tx := m.db.Begin()
-	if tx.Error != nil {
-		return tx.Error
-	}
-	for _, model := range models {
-		tx.Select(selectCol).Updates(model)
-	}
-	return tx.Commit().Error
-
+	return m.BatchUpdatesContext(context.Background(), models, selectCol...)
+}
+// BatchUpdatesContext 在一个事务中逐个更新 models，任意一个失败时回滚，ctx 中有事务时嵌套执行
+func (m MySQLDao) BatchUpdatesContext(ctx context.Context, models []interface{}, selectCol ...string) error {
+	return m.Transaction(ctx, func(ctx context.Context) error {
+		for _, model := range models {
+			if err := m.conn(ctx, nil).Select(selectCol).Updates(model).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 func (m MySQLDao) Find(ctx context.Context, model interface{}, query interface{}, args ...interface{}) error {
-	return m.db.WithContext(ctx).Where(query, args...).Find(model).Error
+	return m.reader(ctx).Where(query, args...).Find(model).Error
 }
 func (m MySQLDao) First(ctx context.Context, model interface{}, query interface{}, args ...interface{}) error {
-	return m.db.WithContext(ctx).Where(query, args...).First(model).Error
+	return m.reader(ctx).Where(query, args...).First(model).Error
 }
 func (m MySQLDao) FirstWithMuiltQuery(model interface{}, query *gorm.DB) error {
 	return query.First(model).Error
 }
 func (m MySQLDao) Last(model interface{}, query interface{}, args ...interface{}) error {
-	return m.db.Where(query, args...).Last(model).Error
+	return m.LastContext(context.Background(), model, query, args...)
+}
+// LastContext 与 Last 相同，在主库或 ctx 中的事务上查询
+func (m MySQLDao) LastContext(ctx context.Context, model interface{}, query interface{}, args ...interface{}) error {
+	return m.conn(ctx, nil).Where(query, args...).Last(model).Error
 }
 func (m MySQLDao) FindAll(model interface{}, query interface{}, args ...interface{}) error {
-	return m.db.Where(query, args...).Find(model).Error
+	return m.FindAllContext(context.Background(), model, query, args...)
+}
+// FindAllContext 与 FindAll 相同，在主库或 ctx 中的事务上查询
+func (m MySQLDao) FindAllContext(ctx context.Context, model interface{}, query interface{}, args ...interface{}) error {
+	return m.conn(ctx, nil).Where(query, args...).Find(model).Error
+}
+func (m MySQLDao) getRenames(model interface{}) ([]columnRename, error) {
+	return columnRenames(model)
+}
+
+// columnRename old_column 标签声明的一次列重命名
+type columnRename struct {
+	Old string
+	New string
 }
-func (m MySQLDao) getRenames(model interface{}) (map[string]string, error) {
+
+// columnRenames 按字段顺序返回模型中 old_column 标签声明的列重命名
+func columnRenames(model interface{}) ([]columnRename, error) {
 	// 获取结构体类型
 	modelType := reflect.TypeOf(model)
 	// modelVal := reflect.ValueOf(model)
@@ -259,7 +366,7 @@ func (m MySQLDao) getRenames(model interface{}) (map[string]string, error) {
 	if modelType.Kind() != reflect.Struct {
 		return nil, fmt.Errorf("%s not a struct type", modelType.Kind().String())
 	}
-	names := make(map[string]string)
+	names := make([]columnRename, 0)
 
 	// 遍历结构体的字段
 	for i := 0; i < modelType.NumField(); i++ {
@@ -273,7 +380,7 @@ func (m MySQLDao) getRenames(model interface{}) (map[string]string, error) {
 			for _, part := range tagParts {
 				kv := strings.Split(part, ":")
 				if len(kv) == 2 && strings.TrimSpace(kv[0]) == "column" {
-					names[oldName] = strings.TrimSpace(kv[1])
+					names = append(names, columnRename{Old: oldName, New: strings.TrimSpace(kv[1])})
 					// value := modelVal.Field(i).Interface()
 					// return strings.TrimSpace(kv[1]), value, nil
 				}
@@ -292,32 +399,43 @@ func (m MySQLDao) AutoMigrate(model interface{}) error {
 		return err
 	}
 	if m.db.Migrator().HasTable(model) {
-		for oldName, newName := range names {
-			if err = m.db.Migrator().RenameColumn(model, oldName, newName); err != nil && !strings.Contains(err.Error(), "Unknown column") {
-				return fmt.Errorf("rename column %s to %s failed:%w", oldName, newName, err)
+		for _, name := range names {
+			if err = m.db.Migrator().RenameColumn(model, name.Old, name.New); err != nil && !strings.Contains(err.Error(), "Unknown column") {
+				return fmt.Errorf("rename column %s to %s failed:%w", name.Old, name.New, err)
 			}
 		}
 	}
 	return m.db.AutoMigrate(model)
 }
 func (m MySQLDao) Count(model interface{}, query interface{}, args ...interface{}) (int64, error) {
+	return m.CountContext(context.Background(), model, query, args...)
+}
+// CountContext 与 Count 相同，读取副本，ctx 为 UsePrimary 或有事务时读取主库
+func (m MySQLDao) CountContext(ctx context.Context, model interface{}, query interface{}, args ...interface{}) (int64, error) {
 	var count int64
-	err := m.db.Where(query, args...).Model(model).Count(&count).Error
+	err := m.reader(ctx).Where(query, args...).Model(model).Count(&count).Error
 	return count, err
 }
 func (m MySQLDao) CreateInBatches(models interface{}) error {
+	return m.CreateInBatchesContext(context.Background(), models)
+}
+// CreateInBatchesContext 与 CreateInBatches 相同，ctx 中有事务时在事务中执行
+func (m MySQLDao) CreateInBatchesContext(ctx context.Context, models interface{}) error {
 	size, err := GetElementCount(models)
 	if err != nil {
 		return err
 	}
-	return m.db.CreateInBatches(models, size).Error
+	return m.conn(ctx, nil).CreateInBatches(models, size).Error
 }
 func (m MySQLDao) Where(query interface{}, args ...interface{}) *gorm.DB {
 	return m.db.Where(query, args...)
 }
 func (m MySQLDao) GroupAndCount(model interface{}, result interface{}, groupField string, selectQuery string, query interface{}, args ...interface{}) error {
-	err := m.db.Model(model).Select(selectQuery).Where(query, args...).Group(groupField).Find(result).Error
-	return err
+	return m.GroupAndCountContext(context.Background(), model, result, groupField, selectQuery, query, args...)
+}
+// GroupAndCountContext 与 GroupAndCount 相同，读取副本，ctx 为 UsePrimary 或有事务时读取主库
+func (m MySQLDao) GroupAndCountContext(ctx context.Context, model interface{}, result interface{}, groupField string, selectQuery string, query interface{}, args ...interface{}) error {
+	return m.reader(ctx).Model(model).Select(selectQuery).Where(query, args...).Group(groupField).Find(result).Error
 }
 func (m MySQLDao) Begin(opts ...*sql.TxOptions) *gorm.DB {
 	return m.db.Begin(opts...)
@@ -402,34 +520,3 @@ func (s JSONField) Value(ctx context.Context, field *schema.Field, dst reflect.V
 	return InterfaceToBytes(fieldValue)
 }
 
-type ValidatorMySQLPlugin struct{}
-
-func (vp *ValidatorMySQLPlugin) Name() string {
-	return "ValidatorPlugin"
-}
-
-func (vp *ValidatorMySQLPlugin) Initialize(db *gorm.DB) error {
-	db.Callback().Create().Before("gorm:create").Register("validator_plugin:before_create", beforeCreateCallback)
-	db.Callback().Update().Before("gorm:update").Register("validator_plugin:before_update", beforeUpdateCallback)
-	return nil
-}
-
-func beforeCreateCallback(db *gorm.DB) {
-	// 校验逻辑同上
-}
-
-func beforeUpdateCallback(db *gorm.DB) {
-	if db.Statement.Schema != nil {
-
-		for _, field := range db.Statement.Schema.Fields {
-
-			value, _ := field.ValueOf(db.Statement.Context, db.Statement.ReflectValue)
-			if str, ok := value.(string); ok && str == "" {
-				_ = db.AddError(errors.New(field.Name + " cannot be empty"))
-			}
-			if num, ok := value.(int); ok && num == 0 {
-				_ = db.AddError(errors.New(field.Name + " cannot be zero"))
-			}
-		}
-	}
-}
@@ -0,0 +1,213 @@
+package tiga
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// defaultScanChunkSize Iterate 与 Chunk 每批读取的行数
+const defaultScanChunkSize = 1000
+
+// ScanProgress 遍历的进度
+type ScanProgress struct {
+	// Rows 已处理的行数
+	Rows int64
+	// Chunks 已处理的批次数，Iterate 按 ScanChunkSize 计算
+	Chunks  int64
+	Elapsed time.Duration
+}
+
+type scanOptions struct {
+	chunkSize   int
+	parallelism int
+	keys        []string
+	desc        bool
+	progress    func(ScanProgress)
+}
+
+// ScanOption Iterate 与 Chunk 的可选配置
+type ScanOption func(*scanOptions)
+
+// ScanChunkSize 每批的行数，默认1000
+func ScanChunkSize(size int) ScanOption {
+	return func(o *scanOptions) {
+		o.chunkSize = size
+	}
+}
+
+// ScanParallelism Chunk 同时处理的批次数，默认1，读取始终按顺序进行
+func ScanParallelism(n int) ScanOption {
+	return func(o *scanOptions) {
+		o.parallelism = n
+	}
+}
+
+// ScanKeys Chunk 按 keys 做 keyset 扫描，组合后必须唯一，默认为 id，见 CursorPagination.Keys
+func ScanKeys(desc bool, keys ...string) ScanOption {
+	return func(o *scanOptions) {
+		o.keys = keys
+		o.desc = desc
+	}
+}
+
+// ScanProgressFunc 每处理完一批后回调进度，回调不会并发执行
+func ScanProgressFunc(fn func(ScanProgress)) ScanOption {
+	return func(o *scanOptions) {
+		o.progress = fn
+	}
+}
+
+func newScanOptions(opts []ScanOption) *scanOptions {
+	o := &scanOptions{chunkSize: defaultScanChunkSize, parallelism: 1}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.chunkSize <= 0 {
+		o.chunkSize = defaultScanChunkSize
+	}
+	if o.parallelism <= 0 {
+		o.parallelism = 1
+	}
+	return o
+}
+
+// Iterate 流式读取满足 cond 的记录并逐行调用 fn，内存中只保留当前行。
+// model 为结构体指针，fn 收到的 row 为同类型的新指针；cond 可以为空、map、结构体或 gorm.Expr。
+// 读取期间会占用一个连接，fn 中不能使用同一个事务执行其他语句，需要在事务中处理时使用 Chunk
+func (m MySQLDao) Iterate(ctx context.Context, model interface{}, cond interface{}, fn func(row interface{}) error, opts ...ScanOption) error {
+	o := newScanOptions(opts)
+	modelType := reflect.TypeOf(model)
+	if modelType.Kind() != reflect.Ptr || modelType.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("model must be a pointer to struct, got %T", model)
+	}
+	db := where(m.reader(ctx).Model(model), cond)
+	rows, err := db.Rows()
+	if err != nil {
+		return fmt.Errorf("iterate %T failed:%w", model, err)
+	}
+	defer rows.Close()
+	start := time.Now()
+	progress := ScanProgress{}
+	report := func() {
+		if o.progress != nil {
+			progress.Elapsed = time.Since(start)
+			o.progress(progress)
+		}
+	}
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		row := reflect.New(modelType.Elem()).Interface()
+		if err := db.ScanRows(rows, row); err != nil {
+			return fmt.Errorf("scan %T failed:%w", model, err)
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+		progress.Rows++
+		if progress.Rows%int64(o.chunkSize) == 0 {
+			progress.Chunks++
+			report()
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate %T failed:%w", model, err)
+	}
+	if progress.Rows%int64(o.chunkSize) != 0 {
+		progress.Chunks++
+		report()
+	}
+	return nil
+}
+
+// Chunk 按排序字段分批读取满足 cond 的记录并调用 fn，chunk 为 []*T，model 为 *T。
+// 读取使用 keyset 扫描，不使用 OFFSET，处理期间新写入的记录不会导致重复或遗漏；
+// ScanParallelism 大于1时最多同时处理 n 批，读取下一批与处理并行进行，内存中最多保留 n+1 批。
+// fn 返回错误或 ctx 取消时停止读取，等待正在处理的批次结束后返回第一个错误
+func (m MySQLDao) Chunk(ctx context.Context, model interface{}, cond interface{}, fn func(ctx context.Context, chunk interface{}) error, opts ...ScanOption) error {
+	o := newScanOptions(opts)
+	modelType := reflect.TypeOf(model)
+	if modelType.Kind() != reflect.Ptr || modelType.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("model must be a pointer to struct, got %T", model)
+	}
+	sliceType := reflect.SliceOf(modelType)
+	pagination := &CursorPagination{PageSize: int32(o.chunkSize), Keys: o.keys, Desc: o.desc, Query: cond}
+	group, gctx := errgroup.WithContext(ctx)
+	group.SetLimit(o.parallelism)
+	start := time.Now()
+	var (
+		mu       sync.Mutex
+		progress ScanProgress
+	)
+	fetch := func() error {
+		for {
+			if err := gctx.Err(); err != nil {
+				return err
+			}
+			chunk := reflect.New(sliceType)
+			db, err := pagination.Apply(m.reader(gctx).Model(model))
+			if err != nil {
+				return err
+			}
+			page, err := pagination.Next(db.Find(chunk.Interface()), chunk.Interface())
+			if err != nil {
+				return fmt.Errorf("chunk %T failed:%w", model, err)
+			}
+			rows := chunk.Elem()
+			if rows.Len() > 0 {
+				// 达到并行上限时阻塞，读取不会超前处理太多批次
+				group.Go(func() error {
+					if err := fn(gctx, rows.Interface()); err != nil {
+						return err
+					}
+					mu.Lock()
+					defer mu.Unlock()
+					progress.Rows += int64(rows.Len())
+					progress.Chunks++
+					if o.progress != nil {
+						progress.Elapsed = time.Since(start)
+						o.progress(progress)
+					}
+					return nil
+				})
+			}
+			if !page.HasMore {
+				return nil
+			}
+			pagination.Cursor = page.NextCursor
+		}
+	}
+	fetchErr := fetch()
+	if err := group.Wait(); err != nil {
+		return err
+	}
+	return fetchErr
+}
+
+// Iterate 流式遍历满足 cond 的记录，见 MySQLDao.Iterate
+func (r *Repository[T]) Iterate(ctx context.Context, cond interface{}, fn func(row *T) error, opts ...ScanOption) error {
+	return r.dao.Iterate(r.scanContext(ctx), new(T), cond, func(row interface{}) error {
+		return fn(row.(*T))
+	}, opts...)
+}
+
+// Chunk 分批遍历满足 cond 的记录，见 MySQLDao.Chunk
+func (r *Repository[T]) Chunk(ctx context.Context, cond interface{}, fn func(ctx context.Context, chunk []*T) error, opts ...ScanOption) error {
+	return r.dao.Chunk(r.scanContext(ctx), new(T), cond, func(ctx context.Context, chunk interface{}) error {
+		return fn(ctx, chunk.([]*T))
+	}, opts...)
+}
+
+// scanContext WithTx 创建的 Repository 在其事务中读取
+func (r *Repository[T]) scanContext(ctx context.Context) context.Context {
+	if r.tx != nil {
+		return ContextWithTx(ctx, r.tx)
+	}
+	return ctx
+}
@@ -0,0 +1,255 @@
+package tiga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"google.golang.org/grpc/metadata"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// CreatedByColumn 与 UpdatedByColumn 审计列，写入时取 context 中的 x-uid 填充
+	CreatedByColumn = "created_by"
+	UpdatedByColumn = "updated_by"
+	// VersionColumn 乐观锁的版本号列，见 MySQLDao.Update
+	VersionColumn = "version"
+
+	optimisticLockKey = "tiga:optimistic_lock"
+	lockVersionKey    = "tiga:lock_version"
+)
+
+// ErrVersionConflict 乐观锁冲突时返回的错误均满足 errors.Is(err, ErrVersionConflict)
+var ErrVersionConflict = errors.New("version conflict")
+
+// VersionConflictError 更新时记录的版本号已经不是 Version，记录已被修改或删除
+type VersionConflictError struct {
+	Model   string
+	Version int64
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("%s version %d conflict, the record has been modified", e.Model, e.Version)
+}
+
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
+
+// uidFromContext 返回请求的 x-uid，与 errors.GetUidFromContext 相同，errors 依赖 tiga 因此不能直接引用
+func uidFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if uids := md.Get("x-uid"); len(uids) > 0 {
+		return uids[0]
+	}
+	return ""
+}
+
+type unscopedKey struct{}
+
+// Unscoped 返回不过滤软删除记录的 context，使用该 context 的查询包含已删除的记录，
+// DeleteContext 与 Repository.Delete 为物理删除，不接收 context 的 Delete 始终为软删除。
+// 模型使用 gorm.DeletedAt 字段时 Find/First/Pagination/Count 等查询默认只返回未删除的记录
+func Unscoped(ctx context.Context) context.Context {
+	return context.WithValue(ctx, unscopedKey{}, true)
+}
+
+func scoped(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if unscoped, _ := ctx.Value(unscopedKey{}).(bool); unscoped {
+		return db.Unscoped()
+	}
+	return db
+}
+
+// Restore 恢复软删除的记录，conds 为空时按 model 的主键恢复
+func (m MySQLDao) Restore(ctx context.Context, model interface{}, conds ...interface{}) error {
+	db := m.conn(Unscoped(ctx), nil)
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return err
+	}
+	var deletedAt *schema.Field
+	for _, field := range stmt.Schema.Fields {
+		if field.FieldType == reflect.TypeOf(gorm.DeletedAt{}) {
+			deletedAt = field
+			break
+		}
+	}
+	if deletedAt == nil {
+		return fmt.Errorf("%s has no soft delete field", stmt.Schema.Name)
+	}
+	db = db.Model(model)
+	if len(conds) > 0 {
+		db = db.Where(conds[0], conds[1:]...)
+	}
+	result := db.Where(clause.Neq{Column: clause.Column{Table: clause.CurrentTable, Name: deletedAt.DBName}, Value: nil}).
+		Update(deletedAt.DBName, nil)
+	if result.Error != nil {
+		return fmt.Errorf("restore %s failed:%w", stmt.Schema.Name, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return &NotFoundError{Model: stmt.Schema.Name, Cond: conds}
+	}
+	return nil
+}
+
+// AuditMySQLPlugin 写入时按 context 中的 x-uid 填充 created_by 与 updated_by 列，
+// 模型没有对应的列或 context 中没有 x-uid 时不做处理，创建时已经赋值的列不会覆盖
+type AuditMySQLPlugin struct{}
+
+func (p *AuditMySQLPlugin) Name() string {
+	return "AuditPlugin"
+}
+
+func (p *AuditMySQLPlugin) Initialize(db *gorm.DB) error {
+	if err := db.Callback().Create().Before("gorm:create").Register("audit_plugin:before_create", auditCreateCallback); err != nil {
+		return err
+	}
+	return db.Callback().Update().Before("gorm:update").Register("audit_plugin:before_update", auditUpdateCallback)
+}
+
+func auditCreateCallback(db *gorm.DB) {
+	stmt := db.Statement
+	uid := uidFromContext(stmt.Context)
+	if db.Error != nil || stmt.Schema == nil || uid == "" {
+		return
+	}
+	for _, name := range []string{CreatedByColumn, UpdatedByColumn} {
+		field := stmt.Schema.LookUpField(name)
+		if field == nil {
+			continue
+		}
+		if _, ok := stmt.Dest.(map[string]interface{}); ok {
+			stmt.SetColumn(field.DBName, uid, true)
+			continue
+		}
+		// 批量创建时逐个填充，保留已经赋值的列
+		switch stmt.ReflectValue.Kind() {
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < stmt.ReflectValue.Len(); i++ {
+				setIfZero(db, field, reflect.Indirect(stmt.ReflectValue.Index(i)), uid)
+			}
+		case reflect.Struct:
+			setIfZero(db, field, stmt.ReflectValue, uid)
+		}
+	}
+}
+
+func setIfZero(db *gorm.DB, field *schema.Field, value reflect.Value, uid string) {
+	if _, zero := field.ValueOf(db.Statement.Context, value); zero {
+		_ = db.AddError(field.Set(db.Statement.Context, value, uid))
+	}
+}
+
+func auditUpdateCallback(db *gorm.DB) {
+	stmt := db.Statement
+	uid := uidFromContext(stmt.Context)
+	if db.Error != nil || stmt.Schema == nil || uid == "" {
+		return
+	}
+	if field := stmt.Schema.LookUpField(UpdatedByColumn); field != nil {
+		setColumn(stmt, field, uid)
+	}
+}
+
+// setColumn 更新时设置列的值，有 Select 限制时将该列加入更新的列中
+func setColumn(stmt *gorm.Statement, field *schema.Field, value interface{}) {
+	stmt.SetColumn(field.DBName, value, true)
+	if selected, restricted := stmt.SelectAndOmitColumns(false, true); restricted {
+		if _, ok := selected[field.DBName]; !ok {
+			stmt.Selects = append(stmt.Selects, field.DBName)
+		}
+	}
+}
+
+// OptimisticLockMySQLPlugin 乐观锁，对开启了乐观锁的更新语句增加 version = 当前版本号 的条件并将版本号加1，
+// 没有更新到记录时返回 *VersionConflictError。当前版本号取自 Model，需要先查询出记录再更新；
+// 版本号为0时视为没有加载版本号，不做检查也不修改版本号，与未开启乐观锁时相同，
+// 因此 version 列需要从1开始，例如 gorm:"default:1"
+type OptimisticLockMySQLPlugin struct{}
+
+func (p *OptimisticLockMySQLPlugin) Name() string {
+	return "OptimisticLockPlugin"
+}
+
+func (p *OptimisticLockMySQLPlugin) Initialize(db *gorm.DB) error {
+	if err := db.Callback().Update().Before("gorm:update").Register("optimistic_lock:before_update", lockBeforeUpdate); err != nil {
+		return err
+	}
+	return db.Callback().Update().After("gorm:update").Register("optimistic_lock:after_update", lockAfterUpdate)
+}
+
+// WithOptimisticLock 对 db 上的更新开启乐观锁，MySQLDao.Update 与 UpdateSelectColumns 默认开启，
+// Model 中的版本号为0时不检查
+func WithOptimisticLock(db *gorm.DB) *gorm.DB {
+	return db.Set(optimisticLockKey, true)
+}
+
+func versionField(db *gorm.DB) *schema.Field {
+	stmt := db.Statement
+	if enabled, _ := db.Get(optimisticLockKey); enabled != true || stmt.Schema == nil || stmt.ReflectValue.Kind() != reflect.Struct {
+		return nil
+	}
+	field := stmt.Schema.LookUpField(VersionColumn)
+	if field == nil {
+		return nil
+	}
+	switch field.FieldType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return field
+	}
+	return nil
+}
+
+func lockBeforeUpdate(db *gorm.DB) {
+	field := versionField(db)
+	if db.Error != nil || field == nil {
+		return
+	}
+	stmt := db.Statement
+	value, zero := field.ValueOf(stmt.Context, stmt.ReflectValue)
+	if zero {
+		return
+	}
+	version := reflect.ValueOf(value).Convert(reflect.TypeOf(int64(0))).Int()
+	stmt.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: version},
+	}})
+	db.InstanceSet(lockVersionKey, version)
+	setColumn(stmt, field, version+1)
+}
+
+func lockAfterUpdate(db *gorm.DB) {
+	field := versionField(db)
+	if field == nil {
+		return
+	}
+	value, ok := db.InstanceGet(lockVersionKey)
+	if !ok || db.Error != nil || db.RowsAffected > 0 {
+		return
+	}
+	version := value.(int64)
+	// 冲突时恢复模型中的版本号，重新查询后可以再次更新
+	stmt := db.Statement
+	_ = field.Set(stmt.Context, stmt.ReflectValue, version)
+	if dest := reflect.Indirect(reflect.ValueOf(stmt.Dest)); dest.Kind() == reflect.Struct && dest.CanAddr() && dest.Type() == stmt.Schema.ModelType {
+		_ = field.Set(stmt.Context, dest, version)
+	}
+	_ = db.AddError(&VersionConflictError{Model: stmt.Schema.Name, Version: version})
+}
+
+// registerModelPlugins 注册 MySQLDao 默认使用的审计与乐观锁插件
+func registerModelPlugins(db *gorm.DB) error {
+	if err := db.Use(&AuditMySQLPlugin{}); err != nil {
+		return err
+	}
+	return db.Use(&OptimisticLockMySQLPlugin{})
+}
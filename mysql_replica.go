@@ -0,0 +1,174 @@
+package tiga
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ReplicaRoundRobin   = "round_robin"
+	ReplicaLeastLatency = "least_latency"
+	// replicaPingTimeout 单次健康检查的超时时间
+	replicaPingTimeout = 2 * time.Second
+)
+
+type primaryKey struct{}
+
+// UsePrimary 返回强制读主库的 context，用于写入后立即读取的场景，避免读到副本同步延迟前的旧数据
+func UsePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+func usePrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	return primary
+}
+
+type replica struct {
+	addr    string
+	db      *gorm.DB
+	healthy atomic.Bool
+	// latency 健康检查延迟的移动平均值，单位纳秒
+	latency atomic.Int64
+}
+
+// replicaSet 只读副本，后台定期检查副本的健康状态
+type replicaSet struct {
+	replicas []*replica
+	policy   string
+	next     atomic.Uint64
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+func newReplicaSet(opts *MySQLOptions, open func(addr string) (*gorm.DB, error)) (*replicaSet, error) {
+	set := &replicaSet{policy: opts.ReplicaPolicy}
+	for _, addr := range opts.Replicas {
+		db, err := open(addr)
+		if err != nil {
+			set.closeDB()
+			return nil, fmt.Errorf("connect to replica %s failed:%w", addr, err)
+		}
+		set.replicas = append(set.replicas, &replica{addr: addr, db: db})
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	set.cancel = cancel
+	// 先同步检查一次，启动后即可使用健康的副本
+	set.check(ctx)
+	interval := opts.HealthCheckInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	set.wg.Add(1)
+	go func() {
+		defer set.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				set.check(ctx)
+			}
+		}
+	}()
+	return set, nil
+}
+
+// check 并发检查所有副本，状态变化时记录日志
+func (s *replicaSet) check(ctx context.Context) {
+	log := GetLogger("mysql")
+	wg := sync.WaitGroup{}
+	for _, r := range s.replicas {
+		wg.Add(1)
+		go func(r *replica) {
+			defer wg.Done()
+			err := r.ping(ctx)
+			if err != nil {
+				if r.healthy.Swap(false) {
+					log.Warnf("replica %s is unhealthy and ejected:%v", r.addr, err)
+				}
+				return
+			}
+			if !r.healthy.Swap(true) {
+				log.Infof("replica %s is healthy", r.addr)
+			}
+		}(r)
+	}
+	wg.Wait()
+}
+
+func (r *replica) ping(ctx context.Context) error {
+	db, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, replicaPingTimeout)
+	defer cancel()
+	start := time.Now()
+	if err := db.PingContext(ctx); err != nil {
+		return err
+	}
+	latency := int64(time.Since(start))
+	if old := r.latency.Load(); old > 0 {
+		latency = (old*4 + latency) / 5
+	}
+	r.latency.Store(latency)
+	return nil
+}
+
+// pick 按策略选择一个健康的副本，没有健康的副本时返回 nil
+func (s *replicaSet) pick() *gorm.DB {
+	if s.policy == ReplicaLeastLatency {
+		var best *replica
+		for _, r := range s.replicas {
+			if r.healthy.Load() && (best == nil || r.latency.Load() < best.latency.Load()) {
+				best = r
+			}
+		}
+		if best == nil {
+			return nil
+		}
+		return best.db
+	}
+	n := uint64(len(s.replicas))
+	start := s.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		if r := s.replicas[(start+i)%n]; r.healthy.Load() {
+			return r.db
+		}
+	}
+	return nil
+}
+
+func (s *replicaSet) closeDB() {
+	for _, r := range s.replicas {
+		if db, err := r.db.DB(); err == nil {
+			_ = db.Close()
+		}
+	}
+}
+
+func (s *replicaSet) close() {
+	s.cancel()
+	s.wg.Wait()
+	s.closeDB()
+}
+
+// reader 返回读请求使用的连接，事务中、UsePrimary 或没有健康的副本时使用主库
+func (m MySQLDao) reader(ctx context.Context) *gorm.DB {
+	if m.replicas == nil || usePrimary(ctx) || TxFromContext(ctx) != nil {
+		return m.conn(ctx, nil)
+	}
+	db := m.replicas.pick()
+	if db == nil {
+		db = m.db
+	}
+	return scoped(ctx, db.WithContext(ctx))
+}
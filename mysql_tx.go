@@ -0,0 +1,94 @@
+package tiga
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand"
+	"time"
+
+	driver "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+const (
+	// txMaxRetries 死锁或锁等待超时后整个事务的最大重试次数
+	txMaxRetries = 3
+	txRetryDelay = 50 * time.Millisecond
+	// txMaxRetryDelay 重试间隔的上限
+	txMaxRetryDelay = time.Second
+)
+
+type txKey struct{}
+
+// ContextWithTx 返回携带事务的 context，MySQLDao 与 Repository 的方法会在该事务中执行
+func ContextWithTx(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFromContext 返回 context 中的事务，不在事务中时返回 nil
+func TxFromContext(ctx context.Context) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx
+	}
+	return nil
+}
+
+// conn 返回执行语句使用的连接，优先级为显式传入的 tx > context 中的事务 > 默认连接
+func (m MySQLDao) conn(ctx context.Context, tx *gorm.DB) *gorm.DB {
+	if tx == nil {
+		tx = TxFromContext(ctx)
+	}
+	if tx == nil {
+		tx = m.db
+	}
+	return scoped(ctx, tx.WithContext(ctx))
+}
+
+// Transaction 在事务中执行 fn，fn 中使用传入的 ctx 调用 MySQLDao 与 Repository 的方法即可共用该事务。
+// fn 返回错误或 panic 时回滚，panic 会继续向上抛出。
+// 嵌套调用时使用 savepoint，内层失败只回滚到 savepoint；
+// 最外层事务遇到死锁或锁等待超时时回滚并按指数退避重试整个 fn，因此 fn 需要可以重复执行
+func (m MySQLDao) Transaction(ctx context.Context, fn func(ctx context.Context) error, opts ...*sql.TxOptions) error {
+	if tx := TxFromContext(ctx); tx != nil {
+		return tx.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return fn(ContextWithTx(ctx, tx))
+		})
+	}
+	delay := txRetryDelay
+	for attempt := 0; ; attempt++ {
+		err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return fn(ContextWithTx(ctx, tx))
+		}, opts...)
+		if err == nil || attempt >= txMaxRetries || !IsRetryableTxError(err) {
+			return err
+		}
+		GetLogger("mysql").Warnf("transaction failed, retry %d/%d:%v", attempt+1, txMaxRetries, err)
+		// 加入随机抖动，避免冲突的事务同时重试再次死锁
+		timer := time.NewTimer(delay/2 + time.Duration(rand.Int63n(int64(delay))))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+		delay *= 2
+		if delay > txMaxRetryDelay {
+			delay = txMaxRetryDelay
+		}
+	}
+}
+
+// IsRetryableTxError 判断是否为可以重试整个事务的错误: MySQL 的死锁(1213)与锁等待超时(1205)，
+// PostgreSQL 的序列化失败(40001)与死锁(40P01)
+func IsRetryableTxError(err error) bool {
+	var mysqlErr *driver.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1213 || mysqlErr.Number == 1205
+	}
+	var stateErr interface{ SQLState() string }
+	if errors.As(err, &stateErr) {
+		return stateErr.SQLState() == "40001" || stateErr.SQLState() == "40P01"
+	}
+	return false
+}
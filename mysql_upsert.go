@@ -0,0 +1,316 @@
+package tiga
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// defaultUpsertBatchSize 批量 upsert 每批的行数
+const defaultUpsertBatchSize = 100
+
+// UpsertOutcome 单条记录 upsert 的结果。
+// MySQL 由影响行数区分；PostgreSQL 与 SQLite 只在值有变化时更新，没有变化的记录为 UpsertUnchanged；
+// 使用 UpsertUpdateExpr 或其他方言时已存在的记录都计为 UpsertUpdated
+type UpsertOutcome int
+
+const (
+	// UpsertUnchanged 记录已存在且值没有变化，或者使用 UpsertDoNothing 时记录已存在
+	UpsertUnchanged UpsertOutcome = iota
+	UpsertInserted
+	UpsertUpdated
+)
+
+func (o UpsertOutcome) String() string {
+	switch o {
+	case UpsertInserted:
+		return "inserted"
+	case UpsertUpdated:
+		return "updated"
+	default:
+		return "unchanged"
+	}
+}
+
+// UpsertResult upsert 的统计结果
+type UpsertResult struct {
+	Inserted  int64
+	Updated   int64
+	Unchanged int64
+	// Approximate 为 true 时各项统计为估算值，见 UpsertWith
+	Approximate bool
+}
+
+// Outcome 单条记录 upsert 的结果，批量时有插入即为 UpsertInserted，其次有更新即为 UpsertUpdated
+func (r *UpsertResult) Outcome() UpsertOutcome {
+	switch {
+	case r.Inserted > 0:
+		return UpsertInserted
+	case r.Updated > 0:
+		return UpsertUpdated
+	default:
+		return UpsertUnchanged
+	}
+}
+
+type upsertOptions struct {
+	conflict   []string
+	update     []string
+	exprs      []clause.Assignment
+	doNothing  bool
+	batchSize  int
+	hasColumns bool
+}
+
+// UpsertOption UpsertWith 的可选配置
+type UpsertOption func(*upsertOptions)
+
+// UpsertOnConflict 冲突判断的列，默认为主键。
+// MySQL 的 ON DUPLICATE KEY UPDATE 不能指定冲突列，任意唯一索引冲突都会更新，这里的列只用于估算批量的统计结果
+func UpsertOnConflict(columns ...string) UpsertOption {
+	return func(o *upsertOptions) {
+		o.conflict = columns
+	}
+}
+
+// UpsertUpdateColumns 冲突时更新为新值的列，默认为除主键、冲突列、primary 标签的字段、创建时间与 created_by 之外的全部列
+func UpsertUpdateColumns(columns ...string) UpsertOption {
+	return func(o *upsertOptions) {
+		o.update = append(o.update, columns...)
+		o.hasColumns = true
+	}
+}
+
+// UpsertUpdateExpr 冲突时将 column 更新为 value，value 可以是 gorm.Expr，例如 gorm.Expr("hits + 1")
+func UpsertUpdateExpr(column string, value interface{}) UpsertOption {
+	return func(o *upsertOptions) {
+		o.exprs = append(o.exprs, clause.Assignment{Column: clause.Column{Name: column}, Value: value})
+		o.hasColumns = true
+	}
+}
+
+// UpsertDoNothing 冲突时不更新，已存在的记录计为 Unchanged
+func UpsertDoNothing() UpsertOption {
+	return func(o *upsertOptions) {
+		o.doNothing = true
+	}
+}
+
+// UpsertBatchSize 批量 upsert 时每批的行数，默认100
+func UpsertBatchSize(size int) UpsertOption {
+	return func(o *upsertOptions) {
+		o.batchSize = size
+	}
+}
+
+// UpsertWith 插入 models，冲突时按 opts 更新，models 为结构体指针或切片，切片按批次在同一个事务中执行。
+// 默认更新的列见 UpsertUpdateColumns，与旧的 Upsert 相同，带 primary 标签的字段不会更新，
+// 但主键与冲突列之外的唯一索引列会被更新，需要保留时使用 UpsertUpdateColumns 指定。
+// MySQL 单条 upsert 的结果由影响行数确定；其他情况会先在事务中查询已存在的冲突键来区分插入与更新，
+// 并发写入同一批记录时统计结果可能不准确，但不影响写入本身。
+// MySQL 批量 upsert 时任意唯一索引冲突都会更新，影响行数也无法区分每条记录的结果，
+// 统计结果由冲突列的查询与影响行数估算，Approximate 为 true
+func (m MySQLDao) UpsertWith(ctx context.Context, models interface{}, opts ...UpsertOption) (*UpsertResult, error) {
+	o := &upsertOptions{batchSize: defaultUpsertBatchSize}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.batchSize <= 0 {
+		o.batchSize = defaultUpsertBatchSize
+	}
+	db := m.conn(ctx, nil)
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(models); err != nil {
+		return nil, fmt.Errorf("parse upsert model failed:%w", err)
+	}
+	conflict, err := conflictFields(stmt.Schema, o.conflict)
+	if err != nil {
+		return nil, err
+	}
+	onConflict := clause.OnConflict{DoNothing: o.doNothing}
+	for _, field := range conflict {
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: field.DBName})
+	}
+	if !o.doNothing {
+		onConflict.DoUpdates = upsertAssignments(stmt.Schema, conflict, o)
+		if len(onConflict.DoUpdates) == 0 {
+			onConflict.DoNothing = true
+		} else if len(o.exprs) == 0 {
+			onConflict.Where = changedWhere(db.Dialector.Name(), stmt.Schema, onConflict.DoUpdates)
+		}
+	}
+	value := reflect.Indirect(reflect.ValueOf(models))
+	rows := 1
+	if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+		rows = value.Len()
+	}
+	result := &UpsertResult{}
+	if rows == 0 {
+		return result, nil
+	}
+	mysqlDialect := db.Dialector.Name() == "mysql"
+	err = m.Transaction(ctx, func(ctx context.Context) error {
+		for start := 0; start < rows; start += o.batchSize {
+			end := start + o.batchSize
+			if end > rows {
+				end = rows
+			}
+			batch := models
+			if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+				batch = value.Slice(start, end).Interface()
+			}
+			n := int64(end - start)
+			// MySQL 单条记录时影响行数 1 为插入、2 为更新、0 为没有变化，不需要预先查询
+			existing := int64(-1)
+			if !mysqlDialect || n > 1 {
+				count, err := countExisting(m.conn(ctx, nil), stmt.Schema, conflict, value, start, end)
+				if err != nil {
+					return err
+				}
+				existing = count
+			}
+			tx := m.conn(ctx, nil).Clauses(onConflict).Create(batch)
+			if tx.Error != nil {
+				return fmt.Errorf("upsert %s failed:%w", stmt.Schema.Name, tx.Error)
+			}
+			affected := tx.RowsAffected
+			var inserted, updated int64
+			switch {
+			case existing < 0:
+				inserted, updated = affected%2, affected/2
+			case mysqlDialect:
+				// MySQL 插入计1行，更新计2行，值没有变化的更新计0行，冲突列之外的唯一索引冲突无法区分，只能估算
+				result.Approximate = true
+				inserted = n - existing
+				if inserted > affected {
+					inserted = affected
+				}
+				updated = (affected - inserted) / 2
+				if updated > n-inserted {
+					updated = n - inserted
+				}
+			default:
+				inserted = n - existing
+				updated = affected - inserted
+			}
+			if updated < 0 {
+				updated = 0
+			}
+			result.Inserted += inserted
+			result.Updated += updated
+			result.Unchanged += n - inserted - updated
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// changedWhere 只在值有变化时更新，没有变化的记录不计入影响行数，MySQL 本身不计入，不需要条件。
+// 更新时间与 updated_by 每次写入都会变化，不参与比较
+func changedWhere(dialect string, s *schema.Schema, set clause.Set) clause.Where {
+	op := ""
+	switch dialect {
+	case DialectPostgres:
+		op = "IS DISTINCT FROM"
+	case DialectSQLite:
+		op = "IS NOT"
+	default:
+		return clause.Where{}
+	}
+	exprs := make([]clause.Expression, 0, len(set))
+	for _, assignment := range set {
+		if field := s.LookUpField(assignment.Column.Name); field != nil && (field.AutoUpdateTime > 0 || field.DBName == UpdatedByColumn) {
+			continue
+		}
+		exprs = append(exprs, clause.Expr{
+			SQL:  "? " + op + " ?",
+			Vars: []interface{}{clause.Column{Table: clause.CurrentTable, Name: assignment.Column.Name}, assignment.Value},
+		})
+	}
+	if len(exprs) == 0 {
+		return clause.Where{}
+	}
+	return clause.Where{Exprs: []clause.Expression{clause.Or(exprs...)}}
+}
+
+// conflictFields 返回冲突列对应的字段，未指定时使用主键
+func conflictFields(s *schema.Schema, columns []string) ([]*schema.Field, error) {
+	if len(columns) == 0 {
+		if len(s.PrimaryFields) == 0 {
+			return nil, fmt.Errorf("%s has no primary key, conflict columns are required", s.Name)
+		}
+		return s.PrimaryFields, nil
+	}
+	fields := make([]*schema.Field, 0, len(columns))
+	for _, column := range columns {
+		field := s.LookUpField(column)
+		if field == nil {
+			return nil, fmt.Errorf("%s has no column %s", s.Name, column)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// upsertAssignments 冲突时更新的列，未指定列与表达式时更新除冲突列、主键与创建相关列之外的全部列
+func upsertAssignments(s *schema.Schema, conflict []*schema.Field, o *upsertOptions) clause.Set {
+	columns := o.update
+	if !o.hasColumns {
+		skip := make(map[string]bool)
+		for _, field := range conflict {
+			skip[field.DBName] = true
+		}
+		for _, field := range s.Fields {
+			if primary := field.Tag.Get("primary"); primary != "" && primary != "-" {
+				// 兼容旧的 Upsert，primary 标签标记的字段作为业务主键不更新
+				continue
+			}
+			if field.DBName == "" || field.PrimaryKey || skip[field.DBName] || !field.Creatable || !field.Updatable ||
+				field.AutoCreateTime > 0 || field.DBName == CreatedByColumn {
+				continue
+			}
+			columns = append(columns, field.DBName)
+		}
+	}
+	return append(clause.AssignmentColumns(columns), o.exprs...)
+}
+
+// countExisting 统计 value[start:end] 中冲突键已经存在的记录数
+func countExisting(db *gorm.DB, s *schema.Schema, conflict []*schema.Field, value reflect.Value, start, end int) (int64, error) {
+	keys := make([]clause.Expression, 0, end-start)
+	for i := start; i < end; i++ {
+		row := value
+		if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+			row = reflect.Indirect(value.Index(i))
+		}
+		exprs := make([]clause.Expression, 0, len(conflict))
+		for _, field := range conflict {
+			v, zero := field.ValueOf(db.Statement.Context, row)
+			if zero && field.AutoIncrement {
+				// 自增主键为零值时一定是插入
+				exprs = nil
+				break
+			}
+			exprs = append(exprs, clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: v})
+		}
+		if exprs != nil {
+			keys = append(keys, clause.And(exprs...))
+		}
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	var count int64
+	err := db.Unscoped().Table(s.Table).Where(clause.Or(keys...)).Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("count existing %s failed:%w", s.Name, err)
+	}
+	return count, nil
+}
@@ -0,0 +1,158 @@
+package tiga
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ModelFieldError 单个字段校验失败的原因，批量写入时 Field 带有 [下标] 前缀
+type ModelFieldError struct {
+	Field  string
+	Reason string
+}
+
+// ModelValidationError 汇总一次写入中所有不满足 validate 标签的字段
+type ModelValidationError struct {
+	Model  string
+	Fields []ModelFieldError
+}
+
+func (e *ModelValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, fmt.Sprintf("%s %s", f.Field, f.Reason))
+	}
+	return fmt.Sprintf("validate %s failed: %s", e.Model, strings.Join(msgs, "; "))
+}
+
+// ValidatorMySQLPlugin 在 Create 与 Update 前按模型字段的 validate 标签校验写入的值，
+// 规则与配置绑定相同(required、min、max、len、regex、oneof/enum、omitempty)，见 validateValue。
+// 只校验实际写入的字段:Create 校验 Select/Omit 后的全部字段，Update 校验 Select 的字段、
+// 结构体中的非零字段或 map 中的key，校验失败时返回 *ModelValidationError 且不执行写入
+type ValidatorMySQLPlugin struct{}
+
+func (vp *ValidatorMySQLPlugin) Name() string {
+	return "ValidatorPlugin"
+}
+
+func (vp *ValidatorMySQLPlugin) Initialize(db *gorm.DB) error {
+	if err := db.Callback().Create().Before("gorm:create").Register("validator_plugin:before_create", beforeCreateCallback); err != nil {
+		return err
+	}
+	return db.Callback().Update().Before("gorm:update").Register("validator_plugin:before_update", beforeUpdateCallback)
+}
+
+// fieldRules 缓存字段解析后的 validate 规则
+var fieldRules sync.Map
+
+func rulesOf(field *schema.Field) []validateRule {
+	if rules, ok := fieldRules.Load(field); ok {
+		return rules.([]validateRule)
+	}
+	rules := parseValidateTag(field.Tag.Get("validate"))
+	fieldRules.Store(field, rules)
+	return rules
+}
+
+func beforeCreateCallback(db *gorm.DB) {
+	validateWrite(db, true)
+}
+
+func beforeUpdateCallback(db *gorm.DB) {
+	validateWrite(db, false)
+}
+
+func validateWrite(db *gorm.DB, create bool) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil {
+		return
+	}
+	verr := &ModelValidationError{Model: stmt.Schema.Name}
+	selected, restricted := stmt.SelectAndOmitColumns(create, !create)
+	switch dest := stmt.Dest.(type) {
+	case map[string]interface{}:
+		validateMap(stmt.Schema, dest, "", verr)
+	case []map[string]interface{}:
+		for i, row := range dest {
+			validateMap(stmt.Schema, row, fmt.Sprintf("[%d]", i), verr)
+		}
+	default:
+		value := stmt.ReflectValue
+		if !create {
+			// Updates(&T{...}) 使用 Dest 中的值，其他类型的结构体不做校验
+			value = reflect.Indirect(reflect.ValueOf(stmt.Dest))
+			if value.Kind() != reflect.Struct || value.Type() != stmt.Schema.ModelType {
+				return
+			}
+		}
+		switch value.Kind() {
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < value.Len(); i++ {
+				validateStruct(stmt, reflect.Indirect(value.Index(i)), selected, restricted, create, fmt.Sprintf("[%d]", i), verr)
+			}
+		case reflect.Struct:
+			validateStruct(stmt, value, selected, restricted, create, "", verr)
+		}
+	}
+	if len(verr.Fields) > 0 {
+		_ = db.AddError(verr)
+	}
+}
+
+// validateStruct 按 gorm 选择写入列的规则校验结构体字段
+func validateStruct(stmt *gorm.Statement, value reflect.Value, selected map[string]bool, restricted bool, create bool, prefix string, verr *ModelValidationError) {
+	for _, field := range stmt.Schema.Fields {
+		rules := rulesOf(field)
+		if len(rules) == 0 || field.DBName == "" {
+			continue
+		}
+		if create && !field.Creatable || !create && !field.Updatable {
+			continue
+		}
+		fieldValue := field.ReflectValueOf(stmt.Context, value)
+		v, ok := selected[field.DBName]
+		if ok && !v {
+			continue
+		}
+		// 更新结构体时没有 Select 的零值字段不会写入
+		if !ok && (restricted || !create && fieldValue.IsZero()) {
+			continue
+		}
+		for _, reason := range validateValue(fieldValue, rules) {
+			verr.Fields = append(verr.Fields, ModelFieldError{Field: prefix + field.DBName, Reason: reason})
+		}
+	}
+}
+
+// validateMap 校验 map 中写入的列，表达式的值不做校验
+func validateMap(s *schema.Schema, values map[string]interface{}, prefix string, verr *ModelValidationError) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := values[key]
+		field := s.LookUpField(key)
+		if field == nil {
+			continue
+		}
+		if _, ok := value.(clause.Expression); ok {
+			continue
+		}
+		rules := rulesOf(field)
+		if len(rules) == 0 {
+			continue
+		}
+		for _, reason := range validateValue(reflect.ValueOf(value), rules) {
+			verr.Fields = append(verr.Fields, ModelFieldError{Field: prefix + field.DBName, Reason: reason})
+		}
+	}
+}
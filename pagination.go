@@ -0,0 +1,134 @@
+package tiga
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	pb "github.com/spark-lence/tiga/rpc/pb"
+	"gorm.io/gorm"
+)
+
+// PageResult 分页查询的结果，跳过统计时 Total 与 TotalPages 为 -1
+type PageResult[T any] struct {
+	Items      []*T  `json:"items"`
+	Total      int64 `json:"total"`
+	Page       int32 `json:"page"`
+	PageSize   int32 `json:"page_size"`
+	TotalPages int32 `json:"total_pages"`
+	// Estimated 为 true 时 Total 为估算值，见 WithEstimatedCount
+	Estimated bool `json:"estimated"`
+}
+
+// ToProto 转换为 pb.Pagination，可以直接作为 MakeAPIResponse 的 data
+func (p *PageResult[T]) ToProto() *pb.Pagination {
+	return &pb.Pagination{
+		Total:      p.Total,
+		Page:       p.Page,
+		PageSize:   p.PageSize,
+		TotalPages: p.TotalPages,
+		Estimated:  p.Estimated,
+	}
+}
+
+type pageOptions struct {
+	skipCount  bool
+	estimated  bool
+	consistent bool
+}
+
+// PageOption Paginate 的可选配置
+type PageOption func(*pageOptions)
+
+// WithoutCount 不统计总数，Total 与 TotalPages 返回 -1
+func WithoutCount() PageOption {
+	return func(o *pageOptions) {
+		o.skipCount = true
+	}
+}
+
+// WithEstimatedCount 没有查询条件时使用 information_schema 中的估算行数代替 COUNT(*)，
+// 适合数据量很大的表，有查询条件或不是 MySQL 时仍然精确统计
+func WithEstimatedCount() PageOption {
+	return func(o *pageOptions) {
+		o.estimated = true
+	}
+}
+
+// WithConsistentCount 在同一个只读事务中执行统计与分页查询，保证总数与数据来自同一个快照
+func WithConsistentCount() PageOption {
+	return func(o *pageOptions) {
+		o.consistent = true
+	}
+}
+
+// Paginate 按 pagination 分页查询 T 并统计满足条件的总数，Page 从1开始
+func Paginate[T any](ctx context.Context, dao *MySQLDao, pagination *Pagination, opts ...PageOption) (*PageResult[T], error) {
+	o := &pageOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	page := pagination.Page
+	if page < 1 {
+		page = 1
+	}
+	result := &PageResult[T]{Items: make([]*T, 0), Page: page, PageSize: pagination.PageSize, Total: -1, TotalPages: -1}
+	query := func(ctx context.Context) error {
+		// 统计与分页使用同一个查询条件
+		base := where(dao.reader(ctx).Model(new(T)), pagination.Query, pagination.Args...)
+		if !o.skipCount {
+			total, estimated, err := dao.count(base.Session(&gorm.Session{}), pagination.Query == nil && o.estimated)
+			if err != nil {
+				return err
+			}
+			result.Total, result.Estimated = total, estimated
+			result.TotalPages = 0
+			if pagination.PageSize > 0 {
+				result.TotalPages = int32((total + int64(pagination.PageSize) - 1) / int64(pagination.PageSize))
+			}
+			if total == 0 && !estimated {
+				return nil
+			}
+		}
+		db := base.Session(&gorm.Session{})
+		if len(pagination.Select) > 0 {
+			db = db.Select(pagination.Select)
+		}
+		return db.Limit(int(pagination.PageSize)).Offset(int(pagination.PageSize * (page - 1))).Find(&result.Items).Error
+	}
+	var err error
+	if o.consistent && !o.skipCount {
+		err = dao.Transaction(ctx, query, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	} else {
+		err = query(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// count 统计 db 的行数，estimate 为 true 时优先使用表的估算行数
+func (m MySQLDao) count(db *gorm.DB, estimate bool) (int64, bool, error) {
+	var total int64
+	if estimate && db.Dialector.Name() == DialectMySQL {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(db.Statement.Model); err != nil {
+			return 0, false, err
+		}
+		var rows sql.NullInt64
+		err := db.Session(&gorm.Session{NewDB: true}).
+			Raw("SELECT TABLE_ROWS FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?", stmt.Table).
+			Scan(&rows).Error
+		if err == nil && rows.Valid {
+			return rows.Int64, true, nil
+		}
+		if err != nil {
+			GetLogger("mysql").Warnf("estimate rows of %s failed, fall back to count:%v", stmt.Table, err)
+		}
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return 0, false, fmt.Errorf("count failed:%w", err)
+	}
+	return total, false, nil
+}
@@ -17,23 +17,39 @@ type RedisDao struct {
 	locker *redislock.Client
 }
 
+// RedisOptions redis 连接配置，对应 settings.yaml 中的 redis 节点
+type RedisOptions struct {
+	Addr        string `config:"addr" validate:"required"`
+	Username    string `config:"username"`
+	Password    string `config:"password"`
+	DB          int    `config:"db" validate:"min=0"`
+	ConnectSize int    `config:"connectSize" default:"10" validate:"min=1"`
+	// Timeout 超时时间，单位秒
+	Timeout int `config:"timeout" default:"5" validate:"min=1"`
+}
+
+func NewRedisOptions(config *Configuration) (*RedisOptions, error) {
+	opts := &RedisOptions{}
+	if err := config.Bind("redis", opts); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
 // NewRdbConfig redis 配置构造函数
 func NewRdbConfig(config *Configuration) *redis.Options {
-	env := config.GetEnv()
-	password := config.GetConfigByEnv(env, "redis.password").(string)
-	username := config.GetConfigByEnv(env, "redis.username").(string)
-	addr := config.GetString(fmt.Sprintf("%s.%s", env, "redis.addr"))
-	db := config.GetInt(fmt.Sprintf("%s.%s", env, "redis.db"))
-
-	connectSize := config.GetInt(fmt.Sprintf("%s.%s", env, "redis.connectSize"))
-	timeout := config.GetInt(fmt.Sprintf("%s.%s", env, "redis.timeout")) * int(time.Second)
+	opts, err := NewRedisOptions(config)
+	if err != nil {
+		panic(err)
+	}
+	timeout := opts.Timeout * int(time.Second)
 	return &redis.Options{
-		Addr:     addr,
-		Password: password, // 密码
-		Username: username, //用户名
-		DB:       db,
+		Addr:     opts.Addr,
+		Password: opts.Password, // 密码
+		Username: opts.Username, //用户名
+		DB:       opts.DB,
 		//连接池容量及闲置连接数量
-		PoolSize:     connectSize, // 连接池最大socket连接数，默认为4倍CPU数， 4 * runtime.NumCPU
+		PoolSize:     opts.ConnectSize, // 连接池最大socket连接数，默认为4倍CPU数， 4 * runtime.NumCPU
 		MinIdleConns: 10,          //在启动阶段创建指定数量的Idle连接，并长期维持idle状态的连接数不少于指定数量；。
 
 		//超时
@@ -64,6 +80,7 @@ func NewRedisDao(config *Configuration) *RedisDao {
 	}
 	return &RedisDao{
 		client: client,
+		config: *config,
 		locker: redislock.New(client),
 	}
 }
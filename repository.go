@@ -0,0 +1,171 @@
+package tiga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNotFound Repository 查询不到记录时返回的错误均满足 errors.Is(err, ErrNotFound)
+var ErrNotFound = errors.New("record not found")
+
+// NotFoundError 记录不存在，Cond 为查询条件
+type NotFoundError struct {
+	Model string
+	Cond  interface{}
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s not found by %v", e.Model, e.Cond)
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// Repository 基于 MySQLDao 的类型安全的数据访问层，T 为gorm模型的结构体类型(非指针)
+type Repository[T any] struct {
+	dao *MySQLDao
+	// tx 不为空时所有操作在该事务中执行，否则使用 context 中的事务，见 MySQLDao.Transaction
+	tx *gorm.DB
+}
+
+func NewRepository[T any](dao *MySQLDao) *Repository[T] {
+	return &Repository[T]{dao: dao}
+}
+
+// WithTx 返回在 tx 中执行的 Repository
+func (r *Repository[T]) WithTx(tx *gorm.DB) *Repository[T] {
+	return &Repository[T]{dao: r.dao, tx: tx}
+}
+
+func (r *Repository[T]) db(ctx context.Context) *gorm.DB {
+	return r.dao.conn(ctx, r.tx)
+}
+
+// reader 读请求使用的连接，配置了副本时见 MySQLDao.reader
+func (r *Repository[T]) reader(ctx context.Context) *gorm.DB {
+	if r.tx != nil {
+		return r.dao.conn(ctx, r.tx)
+	}
+	return r.dao.reader(ctx)
+}
+
+// modelName 用于错误信息的模型名
+func (r *Repository[T]) modelName() string {
+	return reflect.TypeOf((*T)(nil)).Elem().Name()
+}
+
+func (r *Repository[T]) notFound(err error, cond interface{}) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &NotFoundError{Model: r.modelName(), Cond: cond}
+	}
+	return err
+}
+
+// where cond 为空时不添加查询条件，其余规则与 gorm.DB.Where 一致
+func where(db *gorm.DB, cond interface{}, args ...interface{}) *gorm.DB {
+	if cond == nil {
+		return db
+	}
+	return db.Where(cond, args...)
+}
+
+// Get 按主键查询
+func (r *Repository[T]) Get(ctx context.Context, id interface{}) (*T, error) {
+	model := new(T)
+	err := r.reader(ctx).Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).First(model).Error
+	if err != nil {
+		return nil, r.notFound(err, id)
+	}
+	return model, nil
+}
+
+// First 返回第一条满足条件的记录，cond 与 args 的用法同 gorm.DB.Where
+func (r *Repository[T]) First(ctx context.Context, cond interface{}, args ...interface{}) (*T, error) {
+	model := new(T)
+	if err := where(r.reader(ctx), cond, args...).First(model).Error; err != nil {
+		return nil, r.notFound(err, cond)
+	}
+	return model, nil
+}
+
+// FindBy 返回全部满足条件的记录，没有记录时返回空切片
+func (r *Repository[T]) FindBy(ctx context.Context, cond interface{}, args ...interface{}) ([]*T, error) {
+	models := make([]*T, 0)
+	if err := where(r.reader(ctx), cond, args...).Find(&models).Error; err != nil {
+		return nil, err
+	}
+	return models, nil
+}
+
+// List 分页查询，Page 从1开始
+func (r *Repository[T]) List(ctx context.Context, page *Pagination) ([]*T, error) {
+	models := make([]*T, 0)
+	db := r.reader(ctx)
+	if len(page.Select) > 0 {
+		db = db.Select(page.Select)
+	}
+	offset := 0
+	if page.Page > 1 {
+		offset = int(page.PageSize * (page.Page - 1))
+	}
+	err := where(db, page.Query, page.Args...).Limit(int(page.PageSize)).Offset(offset).Find(&models).Error
+	if err != nil {
+		return nil, err
+	}
+	return models, nil
+}
+
+// Page 分页查询并统计总数，见 Paginate
+func (r *Repository[T]) Page(ctx context.Context, page *Pagination, opts ...PageOption) (*PageResult[T], error) {
+	if r.tx != nil {
+		ctx = ContextWithTx(ctx, r.tx)
+	}
+	return Paginate[T](ctx, r.dao, page, opts...)
+}
+
+func (r *Repository[T]) Create(ctx context.Context, model *T) error {
+	return r.db(ctx).Create(model).Error
+}
+
+// Upsert 见 MySQLDao.Upsert，返回 true 表示更新了已有记录
+func (r *Repository[T]) Upsert(ctx context.Context, model *T, updateSelect ...string) (bool, error) {
+	return r.dao.Upsert(ctx, model, r.tx, updateSelect...)
+}
+
+// UpsertAll 批量 upsert，见 MySQLDao.UpsertWith
+func (r *Repository[T]) UpsertAll(ctx context.Context, models []*T, opts ...UpsertOption) (*UpsertResult, error) {
+	if r.tx != nil {
+		ctx = ContextWithTx(ctx, r.tx)
+	}
+	return r.dao.UpsertWith(ctx, models, opts...)
+}
+
+// Update 按 model 的主键更新 value 中的非零值字段，value 可以是 *T 或 map[string]interface{}，
+// T 有 version 列时使用乐观锁，见 MySQLDao.Update
+func (r *Repository[T]) Update(ctx context.Context, model *T, value interface{}) error {
+	return WithOptimisticLock(r.db(ctx)).Model(model).Updates(value).Error
+}
+
+// Delete 按主键删除，记录不存在时返回 *NotFoundError
+func (r *Repository[T]) Delete(ctx context.Context, id interface{}) error {
+	result := r.db(ctx).Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).Delete(new(T))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return &NotFoundError{Model: r.modelName(), Cond: id}
+	}
+	return nil
+}
+
+func (r *Repository[T]) Count(ctx context.Context, cond interface{}, args ...interface{}) (int64, error) {
+	var count int64
+	err := where(r.reader(ctx).Model(new(T)), cond, args...).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,283 @@
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/spark-lence/tiga/rpc/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// AuditActionSet 通过 SetConfig 修改
+	AuditActionSet = "set"
+	// AuditActionRollback 通过 Rollback 恢复
+	AuditActionRollback = "rollback"
+	// auditLogName 审计日志的默认文件名
+	auditLogName = ".settings.audit.log"
+)
+
+// AuditRecord 一次配置修改记录，Old/New 为 nil 表示key不存在
+type AuditRecord struct {
+	Revision uint64      `json:"revision"`
+	Env      string      `json:"env"`
+	Key      string      `json:"key"`
+	Old      interface{} `json:"old,omitempty"`
+	New      interface{} `json:"new,omitempty"`
+	Operator string      `json:"operator"`
+	Action   string      `json:"action"`
+	Time     time.Time   `json:"time"`
+}
+
+// AuditLog 只追加的配置修改记录
+type AuditLog interface {
+	// Append 按顺序分配递增的版本号并写入记录
+	Append(records ...*AuditRecord) error
+	// List 按版本号顺序返回环境的所有记录
+	List(env string) ([]*AuditRecord, error)
+}
+
+// FileAuditLog 以 JSON Lines 格式写入文件的审计日志
+type FileAuditLog struct {
+	path     string
+	mu       sync.Mutex
+	revision uint64
+	loaded   bool
+}
+
+func NewFileAuditLog(path string) *FileAuditLog {
+	return &FileAuditLog{path: path}
+}
+
+func defaultAuditLog(dir string) *FileAuditLog {
+	return NewFileAuditLog(filepath.Join(dir, auditLogName))
+}
+
+func (f *FileAuditLog) readAll() ([]*AuditRecord, error) {
+	file, err := os.Open(f.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	records := make([]*AuditRecord, 0)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.UseNumber()
+		record := &AuditRecord{}
+		if err := decoder.Decode(record); err != nil {
+			return nil, fmt.Errorf("parse audit log %s failed:%w", f.path, err)
+		}
+		record.Old = normalizeNumber(record.Old)
+		record.New = normalizeNumber(record.New)
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// normalizeNumber 将 json.Number 还原为整数或浮点数
+func normalizeNumber(val interface{}) interface{} {
+	switch v := val.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeNumber(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeNumber(item)
+		}
+	}
+	return val
+}
+
+func (f *FileAuditLog) Append(records ...*AuditRecord) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.loaded {
+		all, err := f.readAll()
+		if err != nil {
+			return err
+		}
+		if len(all) > 0 {
+			f.revision = all[len(all)-1].Revision
+		}
+		f.loaded = true
+	}
+	buf := &bytes.Buffer{}
+	revision := f.revision
+	for _, record := range records {
+		revision++
+		record.Revision = revision
+		data, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("encode audit record %s failed:%w", record.Key, err)
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	f.revision = revision
+	return nil
+}
+
+func (f *FileAuditLog) List(env string) ([]*AuditRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	all, err := f.readAll()
+	if err != nil {
+		return nil, err
+	}
+	records := make([]*AuditRecord, 0, len(all))
+	for _, record := range all {
+		if record.Env == env {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// toRecord 转换为响应中的修改记录，调用方没有 PermSecret 权限时敏感配置的值替换为 ******
+func (s *ConfigServer) toRecord(ctx context.Context, record *AuditRecord) (*pb.ConfigRecord, error) {
+	pbRecord := &pb.ConfigRecord{
+		Revision:  record.Revision,
+		Env:       record.Env,
+		Key:       record.Key,
+		Operator:  record.Operator,
+		Action:    record.Action,
+		Timestamp: record.Time.UnixMilli(),
+	}
+	old, new := record.Old, record.New
+	if (isSecret(record.Key, old) || isSecret(record.Key, new)) && !s.allowed(ctx, record.Env, record.Key, PermSecret) {
+		if old != nil {
+			old = redactedValue
+		}
+		if new != nil {
+			new = redactedValue
+		}
+	}
+	var err error
+	if old != nil {
+		if pbRecord.OldValue, err = encodeValue(record.Key, old); err != nil {
+			return nil, err
+		}
+	}
+	if new != nil {
+		if pbRecord.NewValue, err = encodeValue(record.Key, new); err != nil {
+			return nil, err
+		}
+	}
+	return pbRecord, nil
+}
+
+func (s *ConfigServer) toRecords(ctx context.Context, records []*AuditRecord) ([]*pb.ConfigRecord, error) {
+	pbRecords := make([]*pb.ConfigRecord, 0, len(records))
+	for _, record := range records {
+		pbRecord, err := s.toRecord(ctx, record)
+		if err != nil {
+			return nil, err
+		}
+		pbRecords = append(pbRecords, pbRecord)
+	}
+	return pbRecords, nil
+}
+
+// GetHistory 按版本号顺序返回调用方有读权限的配置的修改记录
+func (s *ConfigServer) GetHistory(ctx context.Context, in *pb.HistoryRequest) (*pb.HistoryResponse, error) {
+	config, err := s.config(in.Env)
+	if err != nil {
+		return nil, err
+	}
+	if in.Key != "" {
+		if err := s.authorize(ctx, config.GetEnv(), requestKey(config, in.Key), PermRead); err != nil {
+			return nil, err
+		}
+	}
+	all, err := s.audit.List(config.GetEnv())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "read audit log failed:%v", err)
+	}
+	key := strings.TrimPrefix(strings.ToLower(in.Key), config.GetEnv()+".")
+	records := make([]*AuditRecord, 0, len(all))
+	for _, record := range all {
+		if (key == "" || record.Key == key) && s.allowed(ctx, record.Env, record.Key, PermRead) {
+			records = append(records, record)
+		}
+	}
+	pbRecords, err := s.toRecords(ctx, records)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.HistoryResponse{Records: pbRecords}, nil
+}
+
+// Rollback 撤销环境在 revision 之后的所有修改，回滚本身也会写入审计日志
+func (s *ConfigServer) Rollback(ctx context.Context, in *pb.RollbackRequest) (*pb.RollbackResponse, error) {
+	config, err := s.config(in.Env)
+	if err != nil {
+		return nil, err
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	all, err := s.audit.List(config.GetEnv())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "read audit log failed:%v", err)
+	}
+	if len(all) > 0 && in.Revision > all[len(all)-1].Revision {
+		return nil, status.Errorf(codes.OutOfRange, "revision %d of %s does not exist, latest is %d", in.Revision, config.GetEnv(), all[len(all)-1].Revision)
+	}
+	// 每个key恢复为 revision 之后第一次修改前的值
+	values := make(map[string]interface{})
+	for _, record := range all {
+		if record.Revision <= in.Revision {
+			continue
+		}
+		if _, ok := values[record.Key]; !ok {
+			values[record.Key] = record.Old
+		}
+	}
+	if len(values) == 0 {
+		return &pb.RollbackResponse{}, nil
+	}
+	records, err := s.apply(ctx, config, values, AuditActionRollback)
+	if err != nil {
+		return nil, err
+	}
+	pbRecords, err := s.toRecords(ctx, records)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.RollbackResponse{Records: pbRecords}, nil
+}
@@ -0,0 +1,269 @@
+package rpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spark-lence/tiga"
+	"github.com/spark-lence/tiga/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// ConfigPermission 配置的访问权限
+type ConfigPermission string
+
+const (
+	// PermRead 读取配置，敏感配置会被替换为 ******
+	PermRead ConfigPermission = "read"
+	// PermWrite 修改与回滚配置
+	PermWrite ConfigPermission = "write"
+	// PermSecret 读取敏感配置的原值，例如 *.password 以及密文引用的配置
+	PermSecret ConfigPermission = "secret"
+)
+
+// redactedValue 没有 PermSecret 权限时敏感配置的返回值
+const redactedValue = "******"
+
+// ACLRule 授予 Subject 在 Env 下以 Prefix 开头的配置key的权限，Subject 与 Env 为 * 时匹配所有，
+// Prefix 为空时匹配所有key
+type ACLRule struct {
+	Subject     string             `json:"subject" yaml:"subject"`
+	Env         string             `json:"env" yaml:"env"`
+	Prefix      string             `json:"prefix" yaml:"prefix"`
+	Permissions []ConfigPermission `json:"permissions" yaml:"permissions"`
+}
+
+// ConfigClaims ConfigServer 接受的token payload，使用 tiga.GenerateJWT 签发
+type ConfigClaims struct {
+	Subject string `json:"sub"`
+	Exp     int64  `json:"exp,omitempty"`
+}
+
+type callerKey struct{}
+
+// WithTokenAuth 开启token认证与访问控制，请求需要携带 authorization: Bearer <token>，
+// 开启mTLS时也可以使用客户端证书的 CommonName 作为身份。未开启时所有请求都拥有全部权限。
+// 健康检查服务不需要认证，供 k8s 等探针使用；反射服务会暴露接口定义，仍然需要认证
+func WithTokenAuth(secret string, rules ...ACLRule) ConfigServerOption {
+	return func(s *ConfigServer) {
+		s.authSecret = secret
+		s.acl = append(s.acl, rules...)
+	}
+}
+
+// WithTLS 使用证书启用TLS
+func WithTLS(certFile string, keyFile string) ConfigServerOption {
+	return func(s *ConfigServer) {
+		s.certFile = certFile
+		s.keyFile = keyFile
+	}
+}
+
+// WithClientCA 开启mTLS，只接受由 caFile 中的CA签发的客户端证书，需要同时使用 WithTLS
+func WithClientCA(caFile string) ConfigServerOption {
+	return func(s *ConfigServer) {
+		s.clientCAFile = caFile
+	}
+}
+
+func (s *ConfigServer) authEnabled() bool {
+	return s.authSecret != ""
+}
+
+// tlsConfig 根据 WithTLS 与 WithClientCA 生成TLS配置，未开启TLS时返回 nil
+func (s *ConfigServer) tlsConfig() (*tls.Config, error) {
+	if s.certFile == "" {
+		if s.clientCAFile != "" {
+			return nil, fmt.Errorf("client ca requires tls certificate")
+		}
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load tls certificate failed:%w", err)
+	}
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if s.clientCAFile != "" {
+		pem, err := os.ReadFile(s.clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read client ca %s failed:%w", s.clientCAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", s.clientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// serverOptions 根据TLS与认证配置生成 grpc.Server 的参数
+func (s *ConfigServer) serverOptions() ([]grpc.ServerOption, error) {
+	opts := make([]grpc.ServerOption, 0)
+	tlsConfig, err := s.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	if s.authEnabled() {
+		opts = append(opts, grpc.ChainUnaryInterceptor(s.authUnaryInterceptor), grpc.ChainStreamInterceptor(s.authStreamInterceptor))
+	}
+	return opts, nil
+}
+
+// authenticate 校验token或客户端证书，返回调用方身份
+func (s *ConfigServer) authenticate(ctx context.Context) (string, error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			token := strings.TrimSpace(strings.TrimPrefix(values[0], "Bearer "))
+			claims := &ConfigClaims{}
+			if err := tiga.ParseJWT(token, s.authSecret, claims); err != nil {
+				return "", status.Errorf(codes.Unauthenticated, "invalid token:%v", err)
+			}
+			if claims.Subject == "" {
+				return "", status.Errorf(codes.Unauthenticated, "token has no subject")
+			}
+			return claims.Subject, nil
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.VerifiedChains) > 0 {
+			return info.State.VerifiedChains[0][0].Subject.CommonName, nil
+		}
+	}
+	return "", status.Errorf(codes.Unauthenticated, "missing authorization token")
+}
+
+// healthMethodPrefix 健康检查服务的方法前缀，这些方法不需要认证
+const healthMethodPrefix = "/grpc.health.v1.Health/"
+
+// skipAuth 判断方法是否不需要认证
+func skipAuth(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, healthMethodPrefix)
+}
+
+func (s *ConfigServer) authUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if skipAuth(info.FullMethod) {
+		return handler(ctx, req)
+	}
+	subject, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(context.WithValue(ctx, callerKey{}, subject), req)
+}
+
+type authServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (a *authServerStream) Context() context.Context {
+	return a.ctx
+}
+
+func (s *ConfigServer) authStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if skipAuth(info.FullMethod) {
+		return handler(srv, stream)
+	}
+	subject, err := s.authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authServerStream{ServerStream: stream, ctx: context.WithValue(stream.Context(), callerKey{}, subject)})
+}
+
+// caller 返回调用方身份，开启认证时为token中的 sub，否则为请求中的 x-uid
+func caller(ctx context.Context) string {
+	if subject, ok := ctx.Value(callerKey{}).(string); ok {
+		return subject
+	}
+	return errors.GetUidFromContext(ctx)
+}
+
+func (r ACLRule) match(subject string, env string, key string) bool {
+	return (r.Subject == "*" || r.Subject == subject) && (r.Env == "*" || r.Env == env) && matchKey(r.Prefix, key)
+}
+
+// allowed 判断调用方是否拥有 env 下 key 的权限，未开启认证时总是返回 true
+func (s *ConfigServer) allowed(ctx context.Context, env string, key string, perm ConfigPermission) bool {
+	if !s.authEnabled() {
+		return true
+	}
+	subject := caller(ctx)
+	for _, rule := range s.acl {
+		if !rule.match(subject, env, key) {
+			continue
+		}
+		for _, p := range rule.Permissions {
+			if p == perm {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// allowedEnv 判断调用方在环境下是否有任意读权限
+func (s *ConfigServer) allowedEnv(ctx context.Context, env string) bool {
+	if !s.authEnabled() {
+		return true
+	}
+	subject := caller(ctx)
+	for _, rule := range s.acl {
+		if (rule.Subject == "*" || rule.Subject == subject) && (rule.Env == "*" || rule.Env == env) {
+			for _, p := range rule.Permissions {
+				if p == PermRead {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (s *ConfigServer) authorize(ctx context.Context, env string, key string, perm ConfigPermission) error {
+	if s.allowed(ctx, env, key, perm) {
+		return nil
+	}
+	return status.Errorf(codes.PermissionDenied, "%s has no %s permission on %s of %s", caller(ctx), perm, key, env)
+}
+
+// isSecret 判断配置是否为敏感配置，raw 为未解析密文引用的原始值
+func isSecret(key string, raw interface{}) bool {
+	return tiga.IsSecretKey(key) || tiga.IsSecretRef(raw)
+}
+
+// redact 将调用方没有 PermSecret 权限的敏感配置替换为 ******，map 类型的值逐个key处理
+func (s *ConfigServer) redact(ctx context.Context, config *tiga.Configuration, key string, val interface{}) interface{} {
+	if !s.authEnabled() {
+		return val
+	}
+	return s.redactValue(ctx, config.GetEnv(), config.Effective(), key, val)
+}
+
+func (s *ConfigServer) redactValue(ctx context.Context, env string, raw map[string]interface{}, key string, val interface{}) interface{} {
+	if values, ok := val.(map[string]interface{}); ok {
+		redacted := make(map[string]interface{}, len(values))
+		for sub, item := range values {
+			redacted[sub] = s.redactValue(ctx, env, raw, fmt.Sprintf("%s.%s", key, sub), item)
+		}
+		return redacted
+	}
+	if isSecret(key, raw[key]) && !s.allowed(ctx, env, key, PermSecret) {
+		return redactedValue
+	}
+	return val
+}
@@ -0,0 +1,440 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spark-lence/tiga"
+	pb "github.com/spark-lence/tiga/rpc/pb"
+	"github.com/spf13/cast"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// clientRetryInterval 监听断开后重连的最大间隔
+	clientRetryInterval = 30 * time.Second
+	// watchBatchWait 超过该时间没有收到新的变更时，认为当前版本的变更已经全部收到
+	watchBatchWait = 50 * time.Millisecond
+	defaultCacheTTL     = time.Minute
+	defaultCallTimeout  = 3 * time.Second
+)
+
+type cachedValue struct {
+	data    []byte
+	fetched time.Time
+}
+
+// ConfigClient Config 服务的客户端，读取结果在本地缓存 ttl 时间，
+// 后台通过 WatchConfig 刷新缓存，服务端不可用时返回最后一次读到的值。
+// ConfigClient 同时实现了 tiga.RemoteSource，可以作为 tiga.Configuration 的远程配置源
+type ConfigClient struct {
+	conn     *grpc.ClientConn
+	client   pb.ConfigClient
+	env      string
+	ttl      time.Duration
+	timeout  time.Duration
+	dialOpts []grpc.DialOption
+
+	mu     sync.RWMutex
+	cache  map[string]*cachedValue
+	cancel context.CancelFunc
+}
+
+// ConfigClientOption ConfigClient 的可选配置
+type ConfigClientOption func(*ConfigClient)
+
+// WithCacheTTL 设置本地缓存的有效期，默认1分钟
+func WithCacheTTL(ttl time.Duration) ConfigClientOption {
+	return func(c *ConfigClient) {
+		c.ttl = ttl
+	}
+}
+
+// WithCallTimeout 设置单次请求的超时时间，默认3秒
+func WithCallTimeout(timeout time.Duration) ConfigClientOption {
+	return func(c *ConfigClient) {
+		c.timeout = timeout
+	}
+}
+
+// WithDialOptions 追加建立连接时的参数，默认使用不加密的连接
+func WithDialOptions(opts ...grpc.DialOption) ConfigClientOption {
+	return func(c *ConfigClient) {
+		c.dialOpts = append(c.dialOpts, opts...)
+	}
+}
+
+// WithClientTLS 使用TLS连接服务端，mTLS时在 config 中设置客户端证书
+func WithClientTLS(config *tls.Config) ConfigClientOption {
+	return WithDialOptions(grpc.WithTransportCredentials(credentials.NewTLS(config)))
+}
+
+// tokenCredentials 在每个请求中携带 authorization: Bearer <token>
+type tokenCredentials string
+
+func (t tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+// RequireTransportSecurity 允许在受信任的内网中不使用TLS
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// WithToken 使用 tiga.GenerateJWT 签发的token认证，payload 见 ConfigClaims
+func WithToken(token string) ConfigClientOption {
+	return WithDialOptions(grpc.WithPerRPCCredentials(tokenCredentials(token)))
+}
+
+// NewConfigClient 连接 target 上的 Config 服务并在后台监听 env 的配置变更
+func NewConfigClient(target string, env string, opts ...ConfigClientOption) (*ConfigClient, error) {
+	c := &ConfigClient{
+		env:      env,
+		ttl:      defaultCacheTTL,
+		timeout:  defaultCallTimeout,
+		dialOpts: []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+		cache:    make(map[string]*cachedValue),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	conn, err := grpc.Dial(target, c.dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("dial config server %s failed:%w", target, err)
+	}
+	c.conn = conn
+	c.client = pb.NewConfigClient(conn)
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	go func() {
+		_ = c.watch(ctx, "", 0, c.applyEvent, nil, func() uint64 {
+			c.invalidateAll()
+			return 0
+		})
+	}()
+	return c, nil
+}
+
+// Close 停止后台刷新并关闭连接
+func (c *ConfigClient) Close() error {
+	c.cancel()
+	return c.conn.Close()
+}
+
+// fetch 读取msgpack编码的配置值，缓存过期后访问服务端，服务端不可用时返回缓存中的旧值
+func (c *ConfigClient) fetch(key string) ([]byte, error) {
+	key = strings.ToLower(key)
+	c.mu.RLock()
+	cached, ok := c.cache[key]
+	c.mu.RUnlock()
+	if ok && time.Since(cached.fetched) < c.ttl {
+		return cached.data, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	resp, err := c.client.GetConfig(ctx, &pb.ConfigRequest{Key: key, Env: c.env})
+	if err == nil {
+		c.mu.Lock()
+		c.cache[key] = &cachedValue{data: resp.Value, fetched: time.Now()}
+		c.mu.Unlock()
+		return resp.Value, nil
+	}
+	if status.Code(err) == codes.NotFound {
+		c.mu.Lock()
+		delete(c.cache, key)
+		c.mu.Unlock()
+		return nil, err
+	}
+	if ok {
+		tiga.GetLogger("rpc").Warnf("get config %s failed, use the cached value:%v", key, err)
+		return cached.data, nil
+	}
+	return nil, err
+}
+
+// Get 读取配置值，key 不存在时返回 codes.NotFound 错误
+func (c *ConfigClient) Get(key string) (interface{}, error) {
+	data, err := c.fetch(key)
+	if err != nil {
+		return nil, err
+	}
+	return decodeValue(key, data)
+}
+
+// decodeValue 解码msgpack编码的配置值，整数统一解码为 int64，浮点数为 float64
+func decodeValue(key string, data []byte) (interface{}, error) {
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.UseLooseInterfaceDecoding(true)
+	var val interface{}
+	if err := decoder.Decode(&val); err != nil {
+		return nil, fmt.Errorf("decode config %s failed:%w", key, err)
+	}
+	return val, nil
+}
+
+// GetString 读取失败时返回空字符串
+func (c *ConfigClient) GetString(key string) string {
+	val, err := c.Get(key)
+	if err != nil {
+		return ""
+	}
+	return cast.ToString(val)
+}
+
+// GetInt 读取失败时返回0
+func (c *ConfigClient) GetInt(key string) int {
+	val, err := c.Get(key)
+	if err != nil {
+		return 0
+	}
+	return cast.ToInt(val)
+}
+
+func (c *ConfigClient) GetBool(key string) bool {
+	val, err := c.Get(key)
+	if err != nil {
+		return false
+	}
+	return cast.ToBool(val)
+}
+
+func (c *ConfigClient) GetDuration(key string) time.Duration {
+	val, err := c.Get(key)
+	if err != nil {
+		return 0
+	}
+	return cast.ToDuration(val)
+}
+
+// Unmarshal 将配置值解码到 out，规则与 tiga.Configuration.UnmarshalKey 一致
+func (c *ConfigClient) Unmarshal(key string, out interface{}) error {
+	val, err := c.Get(key)
+	if err != nil {
+		return err
+	}
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:           out,
+		WeaklyTypedInput: true,
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+	})
+	if err != nil {
+		return err
+	}
+	if err := decoder.Decode(val); err != nil {
+		return fmt.Errorf("decode config %s failed:%w", key, err)
+	}
+	return nil
+}
+
+// applyEvent 用推送的新值更新缓存，包含该key的上级配置缓存失效
+func (c *ConfigClient) applyEvent(event *pb.ConfigEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.cache {
+		if key != event.Key && (strings.HasPrefix(event.Key, key+".") || strings.HasPrefix(key, event.Key+".")) {
+			delete(c.cache, key)
+		}
+	}
+	if len(event.NewValue) == 0 {
+		delete(c.cache, event.Key)
+		return
+	}
+	if _, ok := c.cache[event.Key]; ok {
+		c.cache[event.Key] = &cachedValue{data: event.NewValue, fetched: time.Now()}
+	}
+}
+
+// invalidateAll 无法续传时丢弃缓存的有效期，下一次读取时重新访问服务端
+func (c *ConfigClient) invalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, cached := range c.cache {
+		cached.fetched = time.Time{}
+	}
+}
+
+// watch 从 revision 之后持续监听配置变更，revision 为0时从最新版本开始，断开后按指数退避重连并从最后完整收到的版本续传，
+// 版本无法续传时调用 reset 后从其返回的版本重新监听。
+// 同一版本的变更依次调用 onEvent 后再调用一次 flush，版本号变化或 watchBatchWait 内没有新的变更时视为该版本结束
+func (c *ConfigClient) watch(ctx context.Context, prefix string, revision uint64, onEvent func(*pb.ConfigEvent), flush func(), reset func() uint64) error {
+	log := tiga.GetLogger("rpc")
+	retry := time.Second
+	for {
+		stream, err := c.client.WatchConfig(ctx, &pb.WatchConfigRequest{Env: c.env, Prefix: prefix, FromRevision: revision})
+		if err == nil {
+			var received bool
+			revision, received, err = c.receive(ctx, stream, revision, onEvent, flush)
+			if received {
+				retry = time.Second
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if status.Code(err) == codes.OutOfRange {
+			revision = reset()
+		}
+		log.Warnf("watch config of %s failed, retry in %s:%v", c.env, retry, err)
+		timer := time.NewTimer(retry)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		retry *= 2
+		if retry > clientRetryInterval {
+			retry = clientRetryInterval
+		}
+	}
+}
+
+// receive 读取流中的变更直到出错，返回最后一个完整处理的版本号以及是否收到过变更。
+// 流断开时未结束的版本不调用 flush，续传时服务端会重新推送该版本的全部变更
+func (c *ConfigClient) receive(ctx context.Context, stream pb.Config_WatchConfigClient, revision uint64, onEvent func(*pb.ConfigEvent), flush func()) (uint64, bool, error) {
+	events := make(chan *pb.ConfigEvent)
+	errc := make(chan error, 1)
+	go func() {
+		for {
+			event, err := stream.Recv()
+			if err != nil {
+				errc <- err
+				return
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				errc <- ctx.Err()
+				return
+			}
+		}
+	}()
+	var (
+		pending uint64
+		idle    <-chan time.Time
+	)
+	done := func() {
+		if flush != nil {
+			flush()
+		}
+		revision, pending, idle = pending, 0, nil
+	}
+	var received bool
+	for {
+		select {
+		case event := <-events:
+			if pending != 0 && event.Revision != pending {
+				done()
+			}
+			onEvent(event)
+			pending = event.Revision
+			received = true
+			idle = time.After(watchBatchWait)
+		case <-idle:
+			done()
+		case err := <-errc:
+			return revision, received, err
+		}
+	}
+}
+
+// sourceKey 将服务端返回的key转换为 tiga.Configuration 远程层使用的 <env>.a.b
+func (c *ConfigClient) sourceKey(key string) string {
+	if strings.HasPrefix(key, "common.") {
+		return key
+	}
+	return fmt.Sprintf("%s.%s", c.env, key)
+}
+
+// Load 读取环境下的全部配置，实现 tiga.RemoteSource
+func (c *ConfigClient) Load(ctx context.Context) (map[string]interface{}, error) {
+	values, _, err := c.load(ctx)
+	return values, err
+}
+
+// load 读取全部配置，同时返回读取前服务端的版本号，服务端没有返回版本号时为0
+func (c *ConfigClient) load(ctx context.Context) (map[string]interface{}, uint64, error) {
+	var header metadata.MD
+	keys, err := c.client.ListKeys(ctx, &pb.ListKeysRequest{Env: c.env}, grpc.Header(&header))
+	if err != nil {
+		return nil, 0, fmt.Errorf("list config keys of %s failed:%w", c.env, err)
+	}
+	var revision uint64
+	if values := header.Get(revisionHeader); len(values) > 0 {
+		revision, _ = strconv.ParseUint(values[0], 10, 64)
+	}
+	resp, err := c.client.GetConfigs(ctx, &pb.GetConfigsRequest{Env: c.env, Keys: keys.Keys})
+	if err != nil {
+		return nil, 0, fmt.Errorf("get configs of %s failed:%w", c.env, err)
+	}
+	values := make(map[string]interface{}, len(resp.Values))
+	for key, data := range resp.Values {
+		val, err := decodeValue(key, data)
+		if err != nil {
+			return nil, 0, err
+		}
+		values[c.sourceKey(key)] = val
+	}
+	return values, revision, nil
+}
+
+// Watch 监听配置变更并以完整的配置回调，实现 tiga.RemoteSource。
+// 从读取全部配置前的版本开始监听，读取期间的变更会补发，不会遗漏，同一版本的变更只回调一次
+func (c *ConfigClient) Watch(ctx context.Context, onChange func(values map[string]interface{})) error {
+	values, revision, err := c.load(ctx)
+	if err != nil {
+		return err
+	}
+	// 同一版本的多个变更合并后只回调一次，避免应用看到只更新了一部分的配置
+	onEvent := func(event *pb.ConfigEvent) {
+		key := c.sourceKey(event.Key)
+		if len(event.NewValue) == 0 {
+			delete(values, key)
+			return
+		}
+		val, err := decodeValue(event.Key, event.NewValue)
+		if err != nil {
+			tiga.GetLogger("rpc").Errorf("%v", err)
+			return
+		}
+		values[key] = val
+	}
+	flush := func() {
+		onChange(copyValues(values))
+	}
+	reset := func() uint64 {
+		reloaded, revision, err := c.load(ctx)
+		if err != nil {
+			tiga.GetLogger("rpc").Errorf("reload configs of %s failed:%v", c.env, err)
+			return 0
+		}
+		values = reloaded
+		onChange(copyValues(values))
+		return revision
+	}
+	return c.watch(ctx, "", revision, onEvent, flush, reset)
+}
+
+func copyValues(src map[string]interface{}) map[string]interface{} {
+	dst := make(map[string]interface{}, len(src))
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
@@ -0,0 +1,86 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/spark-lence/tiga/rpc/pb"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/grpc"
+)
+
+// fakeConfigClient 返回空的配置并推送固定的变更，推送完成后流保持打开直到 ctx 结束
+type fakeConfigClient struct {
+	pb.ConfigClient
+	events []*pb.ConfigEvent
+}
+
+func (f *fakeConfigClient) ListKeys(ctx context.Context, in *pb.ListKeysRequest, opts ...grpc.CallOption) (*pb.ListKeysResponse, error) {
+	return &pb.ListKeysResponse{}, nil
+}
+
+func (f *fakeConfigClient) GetConfigs(ctx context.Context, in *pb.GetConfigsRequest, opts ...grpc.CallOption) (*pb.GetConfigsResponse, error) {
+	return &pb.GetConfigsResponse{}, nil
+}
+
+func (f *fakeConfigClient) WatchConfig(ctx context.Context, in *pb.WatchConfigRequest, opts ...grpc.CallOption) (pb.Config_WatchConfigClient, error) {
+	return &fakeWatchStream{ctx: ctx, events: f.events}, nil
+}
+
+type fakeWatchStream struct {
+	grpc.ClientStream
+	ctx    context.Context
+	events []*pb.ConfigEvent
+}
+
+func (f *fakeWatchStream) Recv() (*pb.ConfigEvent, error) {
+	if len(f.events) == 0 {
+		<-f.ctx.Done()
+		return nil, f.ctx.Err()
+	}
+	event := f.events[0]
+	f.events = f.events[1:]
+	return event, nil
+}
+
+func encodeTestValue(t *testing.T, val interface{}) []byte {
+	t.Helper()
+	data, err := msgpack.Marshal(val)
+	if err != nil {
+		t.Fatalf("encode %v failed:%v", val, err)
+	}
+	return data
+}
+
+func TestConfigClientWatchOncePerRevision(t *testing.T) {
+	client := &ConfigClient{env: "dev", client: &fakeConfigClient{events: []*pb.ConfigEvent{
+		{Key: "db.host", NewValue: encodeTestValue(t, "h1"), Revision: 2},
+		{Key: "db.port", NewValue: encodeTestValue(t, 3306), Revision: 2},
+		{Key: "db.user", NewValue: encodeTestValue(t, "u"), Revision: 3},
+	}}}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	changes := make(chan map[string]interface{}, 4)
+	go func() {
+		_ = client.Watch(ctx, func(values map[string]interface{}) {
+			changes <- values
+		})
+	}()
+	expected := []int{2, 3}
+	for i, size := range expected {
+		select {
+		case values := <-changes:
+			if len(values) != size {
+				t.Fatalf("change %d: expected %d keys, got %v", i, size, values)
+			}
+		case <-ctx.Done():
+			t.Fatalf("change %d was not delivered", i)
+		}
+	}
+	select {
+	case values := <-changes:
+		t.Fatalf("unexpected extra change %v", values)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
@@ -2,78 +2,366 @@ package rpc
 
 import (
 	"context"
-	"flag"
-	"fmt"
 	"log"
 	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/spark-lence/tiga"
 	pb "github.com/spark-lence/tiga/rpc/pb"
 	"github.com/vmihailenco/msgpack/v5"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
-var (
-	port = flag.Int("port", 50051, "The server port")
-)
+// revisionHeader ListKeys 响应头中的配置版本号，客户端读取全部配置后从该版本开始监听，不会遗漏期间的变更
+const revisionHeader = "x-config-revision"
 
 type ConfigServer struct {
 	pb.UnimplementedConfigServer
+	mu          sync.RWMutex
 	configs     map[string]*tiga.Configuration
+	hubs        map[string]*configHub
 	settingsDir string
+	// env 默认环境，请求中未指定环境时使用
+	env string
+	// writeMu 串行化 SetConfig 与 Rollback
+	writeMu sync.Mutex
+	store   SettingsStore
+	audit   AuditLog
+	// authSecret 为空时不校验调用方身份
+	authSecret   string
+	acl          []ACLRule
+	certFile     string
+	keyFile      string
+	clientCAFile string
+	// addr 与 listener 为服务监听的地址，listener 优先
+	addr     string
+	listener net.Listener
+	// httpAddr 为空时不启动HTTP接口
+	httpAddr   string
+	httpServer *http.Server
+	lifeMu     sync.Mutex
+	server     *grpc.Server
+	health     *health.Server
+	// done 关闭后所有 WatchConfig 流结束，避免阻塞优雅退出
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
-func (s *ConfigServer) GetConfig(ctx context.Context, in *pb.ConfigRequest) (*pb.ConfigResponse, error) {
-	config := s.configs[in.Env]
-	val, err := config.GetValue(in.Key)
+// ConfigServerOption ConfigServer 的可选配置
+type ConfigServerOption func(*ConfigServer)
+
+// WithSettingsStore 设置 SetConfig 的持久化方式，默认写入配置目录下的 settings.<env>.yaml
+func WithSettingsStore(store SettingsStore) ConfigServerOption {
+	return func(s *ConfigServer) {
+		s.store = store
+	}
+}
 
-	if val == nil || err != nil {
-		return &pb.ConfigResponse{}, fmt.Errorf("Not found config key:%s,%w", in.Key, err)
+// WithAuditLog 设置审计日志，默认写入配置目录下的 .settings.audit.log
+func WithAuditLog(audit AuditLog) ConfigServerOption {
+	return func(s *ConfigServer) {
+		s.audit = audit
+	}
+}
+
+// config 返回环境对应的配置，未加载过的环境在第一次请求时从配置目录加载
+func (s *ConfigServer) config(env string) (*tiga.Configuration, error) {
+	if env == "" {
+		env = s.env
+	}
+	s.mu.RLock()
+	config, ok := s.configs[env]
+	s.mu.RUnlock()
+	if ok {
+		return config, nil
+	}
+	if !tiga.ArrayContainsString(s.envs(), env) {
+		return nil, status.Errorf(codes.NotFound, "env %s not found", env)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if config, ok := s.configs[env]; ok {
+		return config, nil
+	}
+	config, err := tiga.LoadSettings(env, s.settingsDir)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "load settings of %s failed:%v", env, err)
+	}
+	if err := config.WatchConfig(); err != nil {
+		log.Printf("watch settings of %s failed:%v", env, err)
+	}
+	s.configs[env] = config
+	return config, nil
+}
+
+// hub 返回环境对应的变更分发器，在第一次监听时创建
+func (s *ConfigServer) hub(env string) (*configHub, error) {
+	if env == "" {
+		env = s.env
+	}
+	config, err := s.config(env)
+	if err != nil {
+		return nil, err
 	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if hub, ok := s.hubs[env]; ok {
+		return hub, nil
+	}
+	hub := newConfigHub(env, config)
+	s.hubs[env] = hub
+	return hub, nil
+}
+
+// envs 返回配置目录中的所有环境
+func (s *ConfigServer) envs() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.configs[s.env].Envs()
+}
+
+func encodeValue(key string, val interface{}) ([]byte, error) {
 	bytesData, err := msgpack.Marshal(val)
 	if err != nil {
-		log.Fatalf("Error encoding to MsgPack: %v", err)
+		return nil, status.Errorf(codes.Internal, "encode config %s to msgpack failed:%v", key, err)
 	}
-	return &pb.ConfigResponse{Value: bytesData}, err
+	return bytesData, nil
+}
+
+// requestKey 请求中的key统一为小写且不含环境前缀，读取时使用 GetScoped，其他环境的key不会命中
+func requestKey(config *tiga.Configuration, key string) string {
+	return strings.TrimPrefix(strings.ToLower(key), config.GetEnv()+".")
 }
-func (s *ConfigServer) SetConfig(ctx context.Context, in *pb.ConfigRequest) (*pb.ConfigResponse, error) {
-	config := s.configs[in.Env]
-	config.SetConfig(in.Key, in.Value, in.Env)
 
+func (s *ConfigServer) GetConfig(ctx context.Context, in *pb.ConfigRequest) (*pb.ConfigResponse, error) {
+	config, err := s.config(in.Env)
+	if err != nil {
+		return nil, err
+	}
+	key := requestKey(config, in.Key)
+	if err := s.authorize(ctx, config.GetEnv(), key, PermRead); err != nil {
+		return nil, err
+	}
+	val := config.GetScoped(key)
+	if val == nil {
+		return nil, status.Errorf(codes.NotFound, "config key %s not found", in.Key)
+	}
+	bytesData, err := encodeValue(in.Key, s.redact(ctx, config, key, val))
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ConfigResponse{Value: bytesData}, nil
+}
+
+// SetConfig 持久化配置并记录审计日志，修改人为调用方身份，见 caller
+func (s *ConfigServer) SetConfig(ctx context.Context, in *pb.ConfigRequest) (*pb.ConfigResponse, error) {
+	config, err := s.config(in.Env)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.setConfig(ctx, config, in.Key, in.Value); err != nil {
+		return nil, err
+	}
 	return &pb.ConfigResponse{}, nil
 }
-func NewConfigServer(settingDir string,env string) *ConfigServer {
-	configs := make(map[string]*tiga.Configuration)
-	configs[env] = tiga.InitSettings(env, settingDir)
-	return &ConfigServer{
-		configs:     configs,
-		settingsDir: settingDir,
+
+// setConfig SetConfig 与HTTP网关共用的写入逻辑
+func (s *ConfigServer) setConfig(ctx context.Context, config *tiga.Configuration, key string, value interface{}) error {
+	key = requestKey(config, key)
+	if err := s.authorize(ctx, config.GetEnv(), key, PermWrite); err != nil {
+		return err
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_, err := s.apply(ctx, config, map[string]interface{}{key: value}, AuditActionSet)
+	return err
+}
+
+// apply 写入一组配置并重载，校验失败时将环境配置文件恢复为写入前的内容，成功后写入审计日志，
+// 审计记录的 Old 为环境配置文件中的原值。调用方必须持有 writeMu
+func (s *ConfigServer) apply(ctx context.Context, config *tiga.Configuration, values map[string]interface{}, action string) ([]*AuditRecord, error) {
+	env := config.GetEnv()
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		if err := s.authorize(ctx, env, key, PermWrite); err != nil {
+			return nil, err
+		}
+		if layer, ok := config.Origin(key); ok && layer != tiga.LayerBase && layer != tiga.LayerEnvFile {
+			return nil, status.Errorf(codes.FailedPrecondition, "config key %s is overridden by %s layer", key, layer)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	// 恢复与审计都以环境配置文件中的值为准，生效值可能来自 settings.yaml，不能写回环境配置文件
+	files := make(map[string]map[string]interface{}, len(keys))
+	olds := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		files[key] = config.LayerValues(tiga.LayerEnvFile, key)
+		olds[key] = fileValue(key, files[key])
+	}
+	restore := func() {
+		for _, key := range keys {
+			if err := s.restoreFile(env, key, files[key]); err != nil {
+				log.Printf("restore config %s of %s failed:%v", key, env, err)
+			}
+		}
+		if _, err := config.Reload(); err != nil {
+			log.Printf("reload settings of %s failed:%v", env, err)
+		}
+	}
+	for _, key := range keys {
+		if err := s.store.Save(env, key, values[key]); err != nil {
+			restore()
+			return nil, status.Errorf(codes.Internal, "save config %s failed:%v", key, err)
+		}
+	}
+	if _, err := config.Reload(); err != nil {
+		restore()
+		return nil, status.Errorf(codes.InvalidArgument, "apply config of %s failed:%v", env, err)
+	}
+	operator := caller(ctx)
+	now := time.Now()
+	records := make([]*AuditRecord, 0, len(keys))
+	for _, key := range keys {
+		records = append(records, &AuditRecord{
+			Env:      env,
+			Key:      key,
+			Old:      olds[key],
+			New:      values[key],
+			Operator: operator,
+			Action:   action,
+			Time:     now,
+		})
+	}
+	if err := s.audit.Append(records...); err != nil {
+		restore()
+		return nil, status.Errorf(codes.Internal, "write audit log failed:%v", err)
+	}
+	return records, nil
+}
+
+// restoreFile 将环境配置文件中的 key 恢复为 values 记录的扁平配置，values 为空时删除该key
+func (s *ConfigServer) restoreFile(env string, key string, values map[string]interface{}) error {
+	if val, ok := values[key]; ok && len(values) == 1 {
+		return s.store.Save(env, key, val)
 	}
+	if err := s.store.Save(env, key, nil); err != nil {
+		return err
+	}
+	for leaf, val := range values {
+		if err := s.store.Save(env, leaf, val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fileValue 将 LayerValues 返回的扁平配置还原为 key 对应的值，配置分支还原为嵌套的map
+func fileValue(key string, values map[string]interface{}) interface{} {
+	if val, ok := values[key]; ok {
+		return val
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	dst := make(map[string]interface{})
+	for leaf, val := range values {
+		parts := strings.Split(strings.TrimPrefix(leaf, key+"."), ".")
+		node := dst
+		for _, part := range parts[:len(parts)-1] {
+			next, ok := node[part].(map[string]interface{})
+			if !ok {
+				next = make(map[string]interface{})
+				node[part] = next
+			}
+			node = next
+		}
+		node[parts[len(parts)-1]] = val
+	}
+	return dst
 }
-func (s *ConfigServer) Start() {
-	flag.Parse()
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
+
+// ListKeys 列出环境下以 prefix 开头的配置key，key 不含环境前缀，响应头 x-config-revision 为读取前的版本号
+func (s *ConfigServer) ListKeys(ctx context.Context, in *pb.ListKeysRequest) (*pb.ListKeysResponse, error) {
+	hub, err := s.hub(in.Env)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		return nil, err
 	}
-	server := grpc.NewServer()
-	pb.RegisterConfigServer(server, s)
-	log.Printf("server listening at %v", lis.Addr())
-	if err := server.Serve(lis); err != nil {
-		log.Fatalf("failed to serve: %v", err)
+	// HTTP网关调用时没有 grpc 流，忽略错误
+	_ = grpc.SetHeader(ctx, metadata.Pairs(revisionHeader, strconv.FormatUint(hub.currentRevision(), 10)))
+	config := hub.config
+	keys := make([]string, 0)
+	for key := range config.Effective() {
+		if strings.HasPrefix(key, in.Prefix) && s.allowed(ctx, config.GetEnv(), key, PermRead) {
+			keys = append(keys, key)
+		}
 	}
+	sort.Strings(keys)
+	return &pb.ListKeysResponse{Keys: keys}, nil
 }
 
-func (s *ConfigServer) Refresh() {
-	flag.Parse()
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
+// GetConfigs 批量读取配置，不存在的key在 Missing 中返回
+func (s *ConfigServer) GetConfigs(ctx context.Context, in *pb.GetConfigsRequest) (*pb.GetConfigsResponse, error) {
+	config, err := s.config(in.Env)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		return nil, err
+	}
+	resp := &pb.GetConfigsResponse{Values: make(map[string][]byte, len(in.Keys))}
+	for _, reqKey := range in.Keys {
+		key := requestKey(config, reqKey)
+		if err := s.authorize(ctx, config.GetEnv(), key, PermRead); err != nil {
+			return nil, err
+		}
+		val := config.GetScoped(key)
+		if val == nil {
+			resp.Missing = append(resp.Missing, reqKey)
+			continue
+		}
+		bytesData, err := encodeValue(reqKey, s.redact(ctx, config, key, val))
+		if err != nil {
+			return nil, err
+		}
+		resp.Values[reqKey] = bytesData
+	}
+	return resp, nil
+}
+
+// ListEnvs 列出调用方有读权限的环境
+func (s *ConfigServer) ListEnvs(ctx context.Context, in *pb.ListEnvsRequest) (*pb.ListEnvsResponse, error) {
+	envs := make([]string, 0)
+	for _, env := range s.envs() {
+		if s.allowedEnv(ctx, env) {
+			envs = append(envs, env)
+		}
+	}
+	return &pb.ListEnvsResponse{Envs: envs}, nil
+}
+
+// NewConfigServer 加载默认环境的配置，其他环境在第一次请求时加载
+func NewConfigServer(settingDir string, env string, opts ...ConfigServerOption) *ConfigServer {
+	env = tiga.ResolveEnv(env)
+	configs := make(map[string]*tiga.Configuration)
+	configs[env] = tiga.InitSettings(env, settingDir)
+	s := &ConfigServer{
+		configs:     configs,
+		hubs:        make(map[string]*configHub),
+		settingsDir: settingDir,
+		env:         env,
+		store:       NewFileSettingsStore(settingDir),
+		audit:       defaultAuditLog(settingDir),
+		done:        make(chan struct{}),
 	}
-	server := grpc.NewServer()
-	pb.RegisterConfigServer(server, s)
-	log.Printf("server listening at %v", lis.Addr())
-	if err := server.Serve(lis); err != nil {
-		log.Fatalf("failed to serve: %v", err)
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
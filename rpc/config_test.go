@@ -0,0 +1,124 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spark-lence/tiga"
+	pb "github.com/spark-lence/tiga/rpc/pb"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func writeSettings(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s failed:%v", name, err)
+		}
+	}
+}
+
+func newTestConfigServer(t *testing.T, opts ...ConfigServerOption) *ConfigServer {
+	t.Helper()
+	dir := t.TempDir()
+	writeSettings(t, dir, map[string]string{
+		"settings.yaml":      "common:\n  region: cn\n",
+		"settings.dev.yaml":  "api:\n  url: http://dev\n",
+		"settings.prod.yaml": "api:\n  url: http://prod\n",
+	})
+	return NewConfigServer(dir, "dev", opts...)
+}
+
+func TestGetConfigDoesNotReadOtherEnv(t *testing.T) {
+	s := newTestConfigServer(t, WithTokenAuth("secret", ACLRule{Subject: "alice", Env: "dev", Permissions: []ConfigPermission{PermRead}}))
+	ctx := context.WithValue(context.Background(), callerKey{}, "alice")
+
+	resp, err := s.GetConfig(ctx, &pb.ConfigRequest{Env: "dev", Key: "api.url"})
+	if err != nil {
+		t.Fatalf("get dev config failed:%v", err)
+	}
+	var url string
+	if err := msgpack.Unmarshal(resp.Value, &url); err != nil || url != "http://dev" {
+		t.Fatalf("expected dev url, got %q:%v", url, err)
+	}
+	if _, err := s.GetConfig(ctx, &pb.ConfigRequest{Env: "dev", Key: "common.region"}); err != nil {
+		t.Fatalf("get common config failed:%v", err)
+	}
+
+	_, err = s.GetConfig(ctx, &pb.ConfigRequest{Env: "dev", Key: "prod.api.url"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound for another env's key, got %v", err)
+	}
+	_, err = s.GetConfig(ctx, &pb.ConfigRequest{Env: "prod", Key: "api.url"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied on prod, got %v", err)
+	}
+	configs, err := s.GetConfigs(ctx, &pb.GetConfigsRequest{Env: "dev", Keys: []string{"prod.api.url"}})
+	if err != nil {
+		t.Fatalf("get configs failed:%v", err)
+	}
+	if len(configs.Values) != 0 || len(configs.Missing) != 1 {
+		t.Fatalf("expected another env's key to be missing, got %v", configs)
+	}
+}
+
+func TestApplyRestoresEnvFile(t *testing.T) {
+	dir := t.TempDir()
+	writeSettings(t, dir, map[string]string{
+		"settings.yaml":     "dev:\n  api:\n    timeout: 3\n",
+		"settings.dev.yaml": "# dev settings\napi:\n  url: http://dev\n",
+	})
+	s := NewConfigServer(dir, "dev")
+	config, err := s.config("dev")
+	if err != nil {
+		t.Fatalf("load config failed:%v", err)
+	}
+	config.AddValidator(func(next *tiga.Configuration) error {
+		if next.GetInt("api.timeout") > 10 {
+			return errors.New("timeout too large")
+		}
+		return nil
+	})
+	envFile := filepath.Join(dir, "settings.dev.yaml")
+	before, err := os.ReadFile(envFile)
+	if err != nil {
+		t.Fatalf("read env file failed:%v", err)
+	}
+	ctx := context.Background()
+	s.writeMu.Lock()
+	_, err = s.apply(ctx, config, map[string]interface{}{"api.timeout": 30, "api.url": "http://bad"}, AuditActionSet)
+	s.writeMu.Unlock()
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", err)
+	}
+	after, err := os.ReadFile(envFile)
+	if err != nil {
+		t.Fatalf("read env file failed:%v", err)
+	}
+	// settings.yaml 中的值不能被写进环境配置文件
+	if string(after) != string(before) {
+		t.Fatalf("env file was not restored:\n%s", after)
+	}
+	if config.GetInt("api.timeout") != 3 {
+		t.Fatalf("expected timeout from settings.yaml, got %v", config.Get("api.timeout"))
+	}
+
+	s.writeMu.Lock()
+	records, err := s.apply(ctx, config, map[string]interface{}{"api.timeout": 5, "api.url": "http://new"}, AuditActionSet)
+	s.writeMu.Unlock()
+	if err != nil {
+		t.Fatalf("apply failed:%v", err)
+	}
+	olds := map[string]interface{}{}
+	for _, record := range records {
+		olds[record.Key] = record.Old
+	}
+	if olds["api.timeout"] != nil || olds["api.url"] != "http://dev" {
+		t.Fatalf("expected audit old values from env file, got %v", olds)
+	}
+}
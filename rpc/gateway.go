@@ -0,0 +1,333 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	pb "github.com/spark-lence/tiga/rpc/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// WithHTTPAddress 同时在 addr 上提供HTTP/JSON接口，路由见 Handler，与gRPC共用TLS配置
+func WithHTTPAddress(addr string) ConfigServerOption {
+	return func(s *ConfigServer) {
+		s.httpAddr = addr
+	}
+}
+
+// configValue PUT 请求体与 GET 单个配置的响应
+type configValue struct {
+	Key   string      `json:"key,omitempty"`
+	Value interface{} `json:"value"`
+}
+
+type configValues struct {
+	Env    string                 `json:"env"`
+	Values map[string]interface{} `json:"values"`
+}
+
+// changeEvent SSE 推送的变更，删除的配置 new 为 null
+type changeEvent struct {
+	Key      string      `json:"key"`
+	Old      interface{} `json:"old"`
+	New      interface{} `json:"new"`
+	Revision uint64      `json:"revision"`
+}
+
+type httpError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Handler 返回与gRPC接口共用认证、权限与审计逻辑的HTTP/JSON接口:
+//
+//	GET /v1/config/{env}?prefix=  读取环境下以 prefix 开头的配置
+//	GET /v1/config/{env}/{key}    读取单个配置
+//	PUT /v1/config/{env}/{key}    修改配置，请求体为 {"value": ...}
+//	GET /v1/watch/{env}?prefix=&from_revision=  以SSE推送配置变更，重连时也可以使用 Last-Event-ID 续传
+//
+// env 为 _ 时使用默认环境，认证信息通过 Authorization: Bearer <token> 或 X-Uid 请求头传递
+func (s *ConfigServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/config/", s.serveConfig)
+	mux.HandleFunc("/v1/watch/", s.serveWatch)
+	return mux
+}
+
+// httpContext 将HTTP请求头转换为gRPC metadata 并完成认证，使 caller 与 authorize 对两种接口行为一致
+func (s *ConfigServer) httpContext(r *http.Request) (context.Context, error) {
+	md := metadata.MD{}
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		md.Set("authorization", auth)
+	}
+	if uid := r.Header.Get("X-Uid"); uid != "" {
+		md.Set("x-uid", uid)
+	}
+	ctx := metadata.NewIncomingContext(r.Context(), md)
+	if r.TLS != nil {
+		ctx = peer.NewContext(ctx, &peer.Peer{AuthInfo: credentials.TLSInfo{State: *r.TLS}})
+	}
+	if !s.authEnabled() {
+		return ctx, nil
+	}
+	subject, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return context.WithValue(ctx, callerKey{}, subject), nil
+}
+
+// pathEnv 拆分 /v1/xxx/{env}/{key} 中的环境与key
+func pathEnv(path string, prefix string) (string, string) {
+	env, key, _ := strings.Cut(strings.TrimPrefix(path, prefix), "/")
+	if env == "_" {
+		env = ""
+	}
+	return env, key
+}
+
+func (s *ConfigServer) serveConfig(w http.ResponseWriter, r *http.Request) {
+	ctx, err := s.httpContext(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	env, key := pathEnv(r.URL.Path, "/v1/config/")
+	switch {
+	case key == "" && r.Method == http.MethodGet:
+		s.httpListConfigs(ctx, w, env, r.URL.Query().Get("prefix"))
+	case key != "" && r.Method == http.MethodGet:
+		s.httpGetConfig(ctx, w, env, key)
+	case key != "" && r.Method == http.MethodPut:
+		s.httpSetConfig(ctx, w, r, env, key)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeJSON(w, http.StatusMethodNotAllowed, &httpError{Code: "MethodNotAllowed", Message: fmt.Sprintf("%s %s is not supported", r.Method, r.URL.Path)})
+	}
+}
+
+func (s *ConfigServer) httpGetConfig(ctx context.Context, w http.ResponseWriter, env string, key string) {
+	resp, err := s.GetConfig(ctx, &pb.ConfigRequest{Env: env, Key: key})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	val, err := decodeValue(key, resp.Value)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &configValue{Key: key, Value: val})
+}
+
+func (s *ConfigServer) httpListConfigs(ctx context.Context, w http.ResponseWriter, env string, prefix string) {
+	config, err := s.config(env)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	keys, err := s.ListKeys(ctx, &pb.ListKeysRequest{Env: env, Prefix: prefix})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	resp, err := s.GetConfigs(ctx, &pb.GetConfigsRequest{Env: env, Keys: keys.Keys})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	values := make(map[string]interface{}, len(resp.Values))
+	for key, data := range resp.Values {
+		if values[key], err = decodeValue(key, data); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, &configValues{Env: config.GetEnv(), Values: values})
+}
+
+func (s *ConfigServer) httpSetConfig(ctx context.Context, w http.ResponseWriter, r *http.Request, env string, key string) {
+	body := &configValue{}
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(body); err != nil {
+		writeError(w, status.Errorf(codes.InvalidArgument, "decode request body failed:%v", err))
+		return
+	}
+	if body.Value == nil {
+		writeError(w, status.Errorf(codes.InvalidArgument, "value of %s is required", key))
+		return
+	}
+	config, err := s.config(env)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := s.setConfig(ctx, config, key, normalizeNumber(body.Value)); err != nil {
+		writeError(w, err)
+		return
+	}
+	s.httpGetConfig(ctx, w, env, key)
+}
+
+func (s *ConfigServer) serveWatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeJSON(w, http.StatusMethodNotAllowed, &httpError{Code: "MethodNotAllowed", Message: fmt.Sprintf("%s %s is not supported", r.Method, r.URL.Path)})
+		return
+	}
+	ctx, err := s.httpContext(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, status.Errorf(codes.Unimplemented, "streaming is not supported"))
+		return
+	}
+	env, _ := pathEnv(r.URL.Path, "/v1/watch/")
+	from := r.URL.Query().Get("from_revision")
+	if lastID := r.Header.Get("Last-Event-ID"); lastID != "" {
+		from = lastID
+	}
+	in := &pb.WatchConfigRequest{Env: env, Prefix: r.URL.Query().Get("prefix")}
+	if from != "" {
+		if in.FromRevision, err = strconv.ParseUint(from, 10, 64); err != nil {
+			writeError(w, status.Errorf(codes.InvalidArgument, "invalid revision %s", from))
+			return
+		}
+	}
+	started := false
+	ready := func() {
+		// 注册监听成功后立即返回响应头，客户端据此确认订阅已建立
+		startStream(w)
+		flusher.Flush()
+		started = true
+	}
+	err = s.watch(ctx, in, ready, func(event *pb.ConfigEvent) error {
+		change := &changeEvent{Key: event.Key, Revision: event.Revision}
+		var err error
+		if len(event.OldValue) > 0 {
+			if change.Old, err = decodeValue(event.Key, event.OldValue); err != nil {
+				return err
+			}
+		}
+		if len(event.NewValue) > 0 {
+			if change.New, err = decodeValue(event.Key, event.NewValue); err != nil {
+				return err
+			}
+		}
+		data, err := json.Marshal(change)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: change\ndata: %s\n\n", event.Revision, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	})
+	if err == nil || r.Context().Err() != nil {
+		return
+	}
+	if !started {
+		writeError(w, err)
+		return
+	}
+	// 响应头已发送，通过 error 事件告知客户端结束原因
+	data, _ := json.Marshal(toHTTPError(err))
+	fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+	flusher.Flush()
+}
+
+func startStream(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+}
+
+// httpStatus gRPC 错误码对应的HTTP状态码
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func toHTTPError(err error) *httpError {
+	st := status.Convert(err)
+	return &httpError{Code: st.Code().String(), Message: st.Message()}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	writeJSON(w, httpStatus(status.Code(err)), toHTTPError(err))
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("write http response failed:%v", err)
+	}
+}
+
+// serveHTTP 在 WithHTTPAddress 指定的地址上启动HTTP服务，未指定时返回 nil
+func (s *ConfigServer) serveHTTP(errCh chan<- error) (*http.Server, error) {
+	if s.httpAddr == "" {
+		return nil, nil
+	}
+	tlsConfig, err := s.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	lis, err := net.Listen("tcp", s.httpAddr)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s failed:%w", s.httpAddr, err)
+	}
+	server := &http.Server{Handler: s.Handler(), TLSConfig: tlsConfig}
+	go func() {
+		var err error
+		if tlsConfig != nil {
+			err = server.ServeTLS(lis, "", "")
+		} else {
+			err = server.Serve(lis)
+		}
+		if err != http.ErrServerClosed {
+			errCh <- err
+		}
+	}()
+	log.Printf("http server listening at %v", lis.Addr())
+	return server, nil
+}
@@ -25,6 +25,18 @@ type ConfigClient interface {
 	// Sends a greeting
 	GetConfig(ctx context.Context, in *ConfigRequest, opts ...grpc.CallOption) (*ConfigResponse, error)
 	SetConfig(ctx context.Context, in *ConfigRequest, opts ...grpc.CallOption) (*ConfigResponse, error)
+	// 列出环境下以 prefix 开头的配置key
+	ListKeys(ctx context.Context, in *ListKeysRequest, opts ...grpc.CallOption) (*ListKeysResponse, error)
+	// 批量读取配置
+	GetConfigs(ctx context.Context, in *GetConfigsRequest, opts ...grpc.CallOption) (*GetConfigsResponse, error)
+	// 列出配置目录中的所有环境
+	ListEnvs(ctx context.Context, in *ListEnvsRequest, opts ...grpc.CallOption) (*ListEnvsResponse, error)
+	// 查询配置的修改记录
+	GetHistory(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*HistoryResponse, error)
+	// 撤销指定版本之后的所有修改
+	Rollback(ctx context.Context, in *RollbackRequest, opts ...grpc.CallOption) (*RollbackResponse, error)
+	// 监听环境下以 prefix 开头的配置变更
+	WatchConfig(ctx context.Context, in *WatchConfigRequest, opts ...grpc.CallOption) (Config_WatchConfigClient, error)
 }
 
 type configClient struct {
@@ -53,6 +65,83 @@ func (c *configClient) SetConfig(ctx context.Context, in *ConfigRequest, opts ..
 	return out, nil
 }
 
+func (c *configClient) ListKeys(ctx context.Context, in *ListKeysRequest, opts ...grpc.CallOption) (*ListKeysResponse, error) {
+	out := new(ListKeysResponse)
+	err := c.cc.Invoke(ctx, "/pb.Config/ListKeys", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *configClient) GetConfigs(ctx context.Context, in *GetConfigsRequest, opts ...grpc.CallOption) (*GetConfigsResponse, error) {
+	out := new(GetConfigsResponse)
+	err := c.cc.Invoke(ctx, "/pb.Config/GetConfigs", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *configClient) ListEnvs(ctx context.Context, in *ListEnvsRequest, opts ...grpc.CallOption) (*ListEnvsResponse, error) {
+	out := new(ListEnvsResponse)
+	err := c.cc.Invoke(ctx, "/pb.Config/ListEnvs", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *configClient) GetHistory(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*HistoryResponse, error) {
+	out := new(HistoryResponse)
+	err := c.cc.Invoke(ctx, "/pb.Config/GetHistory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *configClient) Rollback(ctx context.Context, in *RollbackRequest, opts ...grpc.CallOption) (*RollbackResponse, error) {
+	out := new(RollbackResponse)
+	err := c.cc.Invoke(ctx, "/pb.Config/Rollback", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *configClient) WatchConfig(ctx context.Context, in *WatchConfigRequest, opts ...grpc.CallOption) (Config_WatchConfigClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Config_ServiceDesc.Streams[0], "/pb.Config/WatchConfig", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &configWatchConfigClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Config_WatchConfigClient interface {
+	Recv() (*ConfigEvent, error)
+	grpc.ClientStream
+}
+
+type configWatchConfigClient struct {
+	grpc.ClientStream
+}
+
+func (x *configWatchConfigClient) Recv() (*ConfigEvent, error) {
+	m := new(ConfigEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // ConfigServer is the server API for Config service.
 // All implementations must embed UnimplementedConfigServer
 // for forward compatibility
@@ -60,6 +149,18 @@ type ConfigServer interface {
 	// Sends a greeting
 	GetConfig(context.Context, *ConfigRequest) (*ConfigResponse, error)
 	SetConfig(context.Context, *ConfigRequest) (*ConfigResponse, error)
+	// 列出环境下以 prefix 开头的配置key
+	ListKeys(context.Context, *ListKeysRequest) (*ListKeysResponse, error)
+	// 批量读取配置
+	GetConfigs(context.Context, *GetConfigsRequest) (*GetConfigsResponse, error)
+	// 列出配置目录中的所有环境
+	ListEnvs(context.Context, *ListEnvsRequest) (*ListEnvsResponse, error)
+	// 查询配置的修改记录
+	GetHistory(context.Context, *HistoryRequest) (*HistoryResponse, error)
+	// 撤销指定版本之后的所有修改
+	Rollback(context.Context, *RollbackRequest) (*RollbackResponse, error)
+	// 监听环境下以 prefix 开头的配置变更
+	WatchConfig(*WatchConfigRequest, Config_WatchConfigServer) error
 	mustEmbedUnimplementedConfigServer()
 }
 
@@ -73,6 +174,24 @@ func (UnimplementedConfigServer) GetConfig(context.Context, *ConfigRequest) (*Co
 func (UnimplementedConfigServer) SetConfig(context.Context, *ConfigRequest) (*ConfigResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SetConfig not implemented")
 }
+func (UnimplementedConfigServer) ListKeys(context.Context, *ListKeysRequest) (*ListKeysResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListKeys not implemented")
+}
+func (UnimplementedConfigServer) GetConfigs(context.Context, *GetConfigsRequest) (*GetConfigsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetConfigs not implemented")
+}
+func (UnimplementedConfigServer) ListEnvs(context.Context, *ListEnvsRequest) (*ListEnvsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListEnvs not implemented")
+}
+func (UnimplementedConfigServer) GetHistory(context.Context, *HistoryRequest) (*HistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetHistory not implemented")
+}
+func (UnimplementedConfigServer) Rollback(context.Context, *RollbackRequest) (*RollbackResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Rollback not implemented")
+}
+func (UnimplementedConfigServer) WatchConfig(*WatchConfigRequest, Config_WatchConfigServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchConfig not implemented")
+}
 func (UnimplementedConfigServer) mustEmbedUnimplementedConfigServer() {}
 
 // UnsafeConfigServer may be embedded to opt out of forward compatibility for this service.
@@ -122,6 +241,117 @@ func _Config_SetConfig_Handler(srv interface{}, ctx context.Context, dec func(in
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Config_ListKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConfigServer).ListKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Config/ListKeys",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConfigServer).ListKeys(ctx, req.(*ListKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Config_GetConfigs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetConfigsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConfigServer).GetConfigs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Config/GetConfigs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConfigServer).GetConfigs(ctx, req.(*GetConfigsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Config_ListEnvs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListEnvsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConfigServer).ListEnvs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Config/ListEnvs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConfigServer).ListEnvs(ctx, req.(*ListEnvsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Config_GetHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConfigServer).GetHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Config/GetHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConfigServer).GetHistory(ctx, req.(*HistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Config_Rollback_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RollbackRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConfigServer).Rollback(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Config/Rollback",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConfigServer).Rollback(ctx, req.(*RollbackRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Config_WatchConfig_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchConfigRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ConfigServer).WatchConfig(m, &configWatchConfigServer{stream})
+}
+
+type Config_WatchConfigServer interface {
+	Send(*ConfigEvent) error
+	grpc.ServerStream
+}
+
+type configWatchConfigServer struct {
+	grpc.ServerStream
+}
+
+func (x *configWatchConfigServer) Send(m *ConfigEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // Config_ServiceDesc is the grpc.ServiceDesc for Config service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -137,7 +367,33 @@ var Config_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SetConfig",
 			Handler:    _Config_SetConfig_Handler,
 		},
+		{
+			MethodName: "ListKeys",
+			Handler:    _Config_ListKeys_Handler,
+		},
+		{
+			MethodName: "GetConfigs",
+			Handler:    _Config_GetConfigs_Handler,
+		},
+		{
+			MethodName: "ListEnvs",
+			Handler:    _Config_ListEnvs_Handler,
+		},
+		{
+			MethodName: "GetHistory",
+			Handler:    _Config_GetHistory_Handler,
+		},
+		{
+			MethodName: "Rollback",
+			Handler:    _Config_Rollback_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchConfig",
+			Handler:       _Config_WatchConfig_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "config.proto",
 }
@@ -48,3 +48,26 @@ class RemoteConfigure(metaclass=SingletonMeta):
 
     def set(self, key, value):
         return self.stub.SetConfig(config_pb2.ConfigRequest(key=key, value=value, env=self.env))
+
+    def list_keys(self, prefix="") -> list:
+        return list(self.stub.ListKeys(config_pb2.ListKeysRequest(env=self.env, prefix=prefix)).keys)
+
+    def get_many(self, keys) -> dict:
+        resp = self.stub.GetConfigs(config_pb2.GetConfigsRequest(env=self.env, keys=keys))
+        return {key: msgpack.unpackb(val, raw=False) for key, val in resp.values.items()}
+
+    def list_envs(self) -> list:
+        return list(self.stub.ListEnvs(config_pb2.ListEnvsRequest()).envs)
+
+    def watch(self, prefix="", from_revision=0):
+        request = config_pb2.WatchConfigRequest(env=self.env, prefix=prefix, from_revision=from_revision)
+        for event in self.stub.WatchConfig(request):
+            old = msgpack.unpackb(event.old_value, raw=False) if event.old_value else None
+            new = msgpack.unpackb(event.new_value, raw=False) if event.new_value else None
+            yield event.key, old, new, event.revision
+
+    def history(self, key=""):
+        return list(self.stub.GetHistory(config_pb2.HistoryRequest(env=self.env, key=key)).records)
+
+    def rollback(self, revision):
+        return list(self.stub.Rollback(config_pb2.RollbackRequest(env=self.env, revision=revision)).records)
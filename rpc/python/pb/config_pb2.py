@@ -13,7 +13,7 @@ _sym_db = _symbol_database.Default()
 
 
 
-DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x0c\x63onfig.proto\x12\x02pb\"8\n\rConfigRequest\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\x0b\n\x03\x65nv\x18\x02 \x01(\t\x12\r\n\x05value\x18\x03 \x01(\t\"\x1f\n\x0e\x43onfigResponse\x12\r\n\x05value\x18\x01 \x01(\x0c\x32t\n\x06\x43onfig\x12\x34\n\tGetConfig\x12\x11.pb.ConfigRequest\x1a\x12.pb.ConfigResponse\"\x00\x12\x34\n\tSetConfig\x12\x11.pb.ConfigRequest\x1a\x12.pb.ConfigResponse\"\x00\x42?\n\x11\x63om.sparklence.pbB\x06\x43onfigP\x01Z github.com/spark-lence/common/pbb\x06proto3')
+DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x0c\x63onfig.proto\x12\x02pb\"8\n\rConfigRequest\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\x0b\n\x03\x65nv\x18\x02 \x01(\t\x12\r\n\x05value\x18\x03 \x01(\t\"\x1f\n\x0e\x43onfigResponse\x12\r\n\x05value\x18\x01 \x01(\x0c\".\n\x0fListKeysRequest\x12\x0b\n\x03\x65nv\x18\x01 \x01(\t\x12\x0e\n\x06prefix\x18\x02 \x01(\t\" \n\x10ListKeysResponse\x12\x0c\n\x04keys\x18\x01 \x03(\t\".\n\x11GetConfigsRequest\x12\x0b\n\x03\x65nv\x18\x01 \x01(\t\x12\x0c\n\x04keys\x18\x02 \x03(\t\"\x88\x01\n\x12GetConfigsResponse\x12\x32\n\x06values\x18\x01 \x03(\x0b\x32\".pb.GetConfigsResponse.ValuesEntry\x12\x0f\n\x07missing\x18\x02 \x03(\t\x1a-\n\x0bValuesEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x0c:\x02\x38\x01\"H\n\x12WatchConfigRequest\x12\x0b\n\x03\x65nv\x18\x01 \x01(\t\x12\x0e\n\x06prefix\x18\x02 \x01(\t\x12\x15\n\rfrom_revision\x18\x03 \x01(\x04\"R\n\x0b\x43onfigEvent\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\x11\n\told_value\x18\x02 \x01(\x0c\x12\x11\n\tnew_value\x18\x03 \x01(\x0c\x12\x10\n\x08revision\x18\x04 \x01(\x04\"*\n\x0eHistoryRequest\x12\x0b\n\x03\x65nv\x18\x01 \x01(\t\x12\x0b\n\x03key\x18\x02 \x01(\t\"\x95\x01\n\x0c\x43onfigRecord\x12\x10\n\x08revision\x18\x01 \x01(\x04\x12\x0b\n\x03\x65nv\x18\x02 \x01(\t\x12\x0b\n\x03key\x18\x03 \x01(\t\x12\x11\n\told_value\x18\x04 \x01(\x0c\x12\x11\n\tnew_value\x18\x05 \x01(\x0c\x12\x10\n\x08operator\x18\x06 \x01(\t\x12\x0e\n\x06\x61\x63tion\x18\x07 \x01(\t\x12\x11\n\ttimestamp\x18\x08 \x01(\x03\"4\n\x0fHistoryResponse\x12!\n\x07records\x18\x01 \x03(\x0b\x32\x10.pb.ConfigRecord\"0\n\x0fRollbackRequest\x12\x0b\n\x03\x65nv\x18\x01 \x01(\t\x12\x10\n\x08revision\x18\x02 \x01(\x04\"5\n\x10RollbackResponse\x12!\n\x07records\x18\x01 \x03(\x0b\x32\x10.pb.ConfigRecord\"\x11\n\x0fListEnvsRequest\" \n\x10ListEnvsResponse\x12\x0c\n\x04\x65nvs\x18\x01 \x03(\t2\xd3\x03\n\x06\x43onfig\x12\x34\n\tGetConfig\x12\x11.pb.ConfigRequest\x1a\x12.pb.ConfigResponse\"\x00\x12\x34\n\tSetConfig\x12\x11.pb.ConfigRequest\x1a\x12.pb.ConfigResponse\"\x00\x12\x37\n\x08ListKeys\x12\x13.pb.ListKeysRequest\x1a\x14.pb.ListKeysResponse\"\x00\x12=\n\nGetConfigs\x12\x15.pb.GetConfigsRequest\x1a\x16.pb.GetConfigsResponse\"\x00\x12\x37\n\x08ListEnvs\x12\x13.pb.ListEnvsRequest\x1a\x14.pb.ListEnvsResponse\"\x00\x12\x37\n\nGetHistory\x12\x12.pb.HistoryRequest\x1a\x13.pb.HistoryResponse\"\x00\x12\x37\n\x08Rollback\x12\x13.pb.RollbackRequest\x1a\x14.pb.RollbackResponse\"\x00\x12:\n\x0bWatchConfig\x12\x16.pb.WatchConfigRequest\x1a\x0f.pb.ConfigEvent\"\x00\x30\x01\x42?\n\x11\x63om.sparklence.pbB\x06\x43onfigP\x01Z github.com/spark-lence/common/pbb\x06proto3')
 
 _globals = globals()
 _builder.BuildMessageAndEnumDescriptors(DESCRIPTOR, _globals)
@@ -21,10 +21,40 @@ _builder.BuildTopDescriptorsAndMessages(DESCRIPTOR, 'config_pb2', _globals)
 if _descriptor._USE_C_DESCRIPTORS == False:
   DESCRIPTOR._options = None
   DESCRIPTOR._serialized_options = b'\n\021com.sparklence.pbB\006ConfigP\001Z github.com/spark-lence/common/pb'
+  _globals['_GETCONFIGSRESPONSE_VALUESENTRY']._options = None
+  _globals['_GETCONFIGSRESPONSE_VALUESENTRY']._serialized_options = b'8\001'
   _globals['_CONFIGREQUEST']._serialized_start=20
   _globals['_CONFIGREQUEST']._serialized_end=76
   _globals['_CONFIGRESPONSE']._serialized_start=78
   _globals['_CONFIGRESPONSE']._serialized_end=109
-  _globals['_CONFIG']._serialized_start=111
-  _globals['_CONFIG']._serialized_end=227
+  _globals['_LISTKEYSREQUEST']._serialized_start=111
+  _globals['_LISTKEYSREQUEST']._serialized_end=157
+  _globals['_LISTKEYSRESPONSE']._serialized_start=159
+  _globals['_LISTKEYSRESPONSE']._serialized_end=191
+  _globals['_GETCONFIGSREQUEST']._serialized_start=193
+  _globals['_GETCONFIGSREQUEST']._serialized_end=239
+  _globals['_GETCONFIGSRESPONSE']._serialized_start=242
+  _globals['_GETCONFIGSRESPONSE']._serialized_end=378
+  _globals['_GETCONFIGSRESPONSE_VALUESENTRY']._serialized_start=333
+  _globals['_GETCONFIGSRESPONSE_VALUESENTRY']._serialized_end=378
+  _globals['_WATCHCONFIGREQUEST']._serialized_start=380
+  _globals['_WATCHCONFIGREQUEST']._serialized_end=452
+  _globals['_CONFIGEVENT']._serialized_start=454
+  _globals['_CONFIGEVENT']._serialized_end=536
+  _globals['_HISTORYREQUEST']._serialized_start=538
+  _globals['_HISTORYREQUEST']._serialized_end=580
+  _globals['_CONFIGRECORD']._serialized_start=583
+  _globals['_CONFIGRECORD']._serialized_end=732
+  _globals['_HISTORYRESPONSE']._serialized_start=734
+  _globals['_HISTORYRESPONSE']._serialized_end=786
+  _globals['_ROLLBACKREQUEST']._serialized_start=788
+  _globals['_ROLLBACKREQUEST']._serialized_end=836
+  _globals['_ROLLBACKRESPONSE']._serialized_start=838
+  _globals['_ROLLBACKRESPONSE']._serialized_end=891
+  _globals['_LISTENVSREQUEST']._serialized_start=893
+  _globals['_LISTENVSREQUEST']._serialized_end=910
+  _globals['_LISTENVSRESPONSE']._serialized_start=912
+  _globals['_LISTENVSRESPONSE']._serialized_end=944
+  _globals['_CONFIG']._serialized_start=947
+  _globals['_CONFIG']._serialized_end=1414
 # @@protoc_insertion_point(module_scope)
@@ -24,6 +24,36 @@ class ConfigStub(object):
                 request_serializer=config__pb2.ConfigRequest.SerializeToString,
                 response_deserializer=config__pb2.ConfigResponse.FromString,
                 )
+        self.ListKeys = channel.unary_unary(
+                '/pb.Config/ListKeys',
+                request_serializer=config__pb2.ListKeysRequest.SerializeToString,
+                response_deserializer=config__pb2.ListKeysResponse.FromString,
+                )
+        self.GetConfigs = channel.unary_unary(
+                '/pb.Config/GetConfigs',
+                request_serializer=config__pb2.GetConfigsRequest.SerializeToString,
+                response_deserializer=config__pb2.GetConfigsResponse.FromString,
+                )
+        self.ListEnvs = channel.unary_unary(
+                '/pb.Config/ListEnvs',
+                request_serializer=config__pb2.ListEnvsRequest.SerializeToString,
+                response_deserializer=config__pb2.ListEnvsResponse.FromString,
+                )
+        self.GetHistory = channel.unary_unary(
+                '/pb.Config/GetHistory',
+                request_serializer=config__pb2.HistoryRequest.SerializeToString,
+                response_deserializer=config__pb2.HistoryResponse.FromString,
+                )
+        self.Rollback = channel.unary_unary(
+                '/pb.Config/Rollback',
+                request_serializer=config__pb2.RollbackRequest.SerializeToString,
+                response_deserializer=config__pb2.RollbackResponse.FromString,
+                )
+        self.WatchConfig = channel.unary_stream(
+                '/pb.Config/WatchConfig',
+                request_serializer=config__pb2.WatchConfigRequest.SerializeToString,
+                response_deserializer=config__pb2.ConfigEvent.FromString,
+                )
 
 
 class ConfigServicer(object):
@@ -42,6 +72,48 @@ class ConfigServicer(object):
         context.set_details('Method not implemented!')
         raise NotImplementedError('Method not implemented!')
 
+    def ListKeys(self, request, context):
+        """列出环境下以 prefix 开头的配置key
+        """
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details('Method not implemented!')
+        raise NotImplementedError('Method not implemented!')
+
+    def GetConfigs(self, request, context):
+        """批量读取配置
+        """
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details('Method not implemented!')
+        raise NotImplementedError('Method not implemented!')
+
+    def ListEnvs(self, request, context):
+        """列出配置目录中的所有环境
+        """
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details('Method not implemented!')
+        raise NotImplementedError('Method not implemented!')
+
+    def GetHistory(self, request, context):
+        """查询配置的修改记录
+        """
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details('Method not implemented!')
+        raise NotImplementedError('Method not implemented!')
+
+    def Rollback(self, request, context):
+        """撤销指定版本之后的所有修改
+        """
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details('Method not implemented!')
+        raise NotImplementedError('Method not implemented!')
+
+    def WatchConfig(self, request, context):
+        """监听环境下以 prefix 开头的配置变更
+        """
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details('Method not implemented!')
+        raise NotImplementedError('Method not implemented!')
+
 
 def add_ConfigServicer_to_server(servicer, server):
     rpc_method_handlers = {
@@ -55,6 +127,36 @@ def add_ConfigServicer_to_server(servicer, server):
                     request_deserializer=config__pb2.ConfigRequest.FromString,
                     response_serializer=config__pb2.ConfigResponse.SerializeToString,
             ),
+            'ListKeys': grpc.unary_unary_rpc_method_handler(
+                    servicer.ListKeys,
+                    request_deserializer=config__pb2.ListKeysRequest.FromString,
+                    response_serializer=config__pb2.ListKeysResponse.SerializeToString,
+            ),
+            'GetConfigs': grpc.unary_unary_rpc_method_handler(
+                    servicer.GetConfigs,
+                    request_deserializer=config__pb2.GetConfigsRequest.FromString,
+                    response_serializer=config__pb2.GetConfigsResponse.SerializeToString,
+            ),
+            'ListEnvs': grpc.unary_unary_rpc_method_handler(
+                    servicer.ListEnvs,
+                    request_deserializer=config__pb2.ListEnvsRequest.FromString,
+                    response_serializer=config__pb2.ListEnvsResponse.SerializeToString,
+            ),
+            'GetHistory': grpc.unary_unary_rpc_method_handler(
+                    servicer.GetHistory,
+                    request_deserializer=config__pb2.HistoryRequest.FromString,
+                    response_serializer=config__pb2.HistoryResponse.SerializeToString,
+            ),
+            'Rollback': grpc.unary_unary_rpc_method_handler(
+                    servicer.Rollback,
+                    request_deserializer=config__pb2.RollbackRequest.FromString,
+                    response_serializer=config__pb2.RollbackResponse.SerializeToString,
+            ),
+            'WatchConfig': grpc.unary_stream_rpc_method_handler(
+                    servicer.WatchConfig,
+                    request_deserializer=config__pb2.WatchConfigRequest.FromString,
+                    response_serializer=config__pb2.ConfigEvent.SerializeToString,
+            ),
     }
     generic_handler = grpc.method_handlers_generic_handler(
             'pb.Config', rpc_method_handlers)
@@ -98,3 +200,105 @@ class Config(object):
             config__pb2.ConfigResponse.FromString,
             options, channel_credentials,
             insecure, call_credentials, compression, wait_for_ready, timeout, metadata)
+
+    @staticmethod
+    def ListKeys(request,
+            target,
+            options=(),
+            channel_credentials=None,
+            call_credentials=None,
+            insecure=False,
+            compression=None,
+            wait_for_ready=None,
+            timeout=None,
+            metadata=None):
+        return grpc.experimental.unary_unary(request, target, '/pb.Config/ListKeys',
+            config__pb2.ListKeysRequest.SerializeToString,
+            config__pb2.ListKeysResponse.FromString,
+            options, channel_credentials,
+            insecure, call_credentials, compression, wait_for_ready, timeout, metadata)
+
+    @staticmethod
+    def GetConfigs(request,
+            target,
+            options=(),
+            channel_credentials=None,
+            call_credentials=None,
+            insecure=False,
+            compression=None,
+            wait_for_ready=None,
+            timeout=None,
+            metadata=None):
+        return grpc.experimental.unary_unary(request, target, '/pb.Config/GetConfigs',
+            config__pb2.GetConfigsRequest.SerializeToString,
+            config__pb2.GetConfigsResponse.FromString,
+            options, channel_credentials,
+            insecure, call_credentials, compression, wait_for_ready, timeout, metadata)
+
+    @staticmethod
+    def ListEnvs(request,
+            target,
+            options=(),
+            channel_credentials=None,
+            call_credentials=None,
+            insecure=False,
+            compression=None,
+            wait_for_ready=None,
+            timeout=None,
+            metadata=None):
+        return grpc.experimental.unary_unary(request, target, '/pb.Config/ListEnvs',
+            config__pb2.ListEnvsRequest.SerializeToString,
+            config__pb2.ListEnvsResponse.FromString,
+            options, channel_credentials,
+            insecure, call_credentials, compression, wait_for_ready, timeout, metadata)
+
+    @staticmethod
+    def GetHistory(request,
+            target,
+            options=(),
+            channel_credentials=None,
+            call_credentials=None,
+            insecure=False,
+            compression=None,
+            wait_for_ready=None,
+            timeout=None,
+            metadata=None):
+        return grpc.experimental.unary_unary(request, target, '/pb.Config/GetHistory',
+            config__pb2.HistoryRequest.SerializeToString,
+            config__pb2.HistoryResponse.FromString,
+            options, channel_credentials,
+            insecure, call_credentials, compression, wait_for_ready, timeout, metadata)
+
+    @staticmethod
+    def Rollback(request,
+            target,
+            options=(),
+            channel_credentials=None,
+            call_credentials=None,
+            insecure=False,
+            compression=None,
+            wait_for_ready=None,
+            timeout=None,
+            metadata=None):
+        return grpc.experimental.unary_unary(request, target, '/pb.Config/Rollback',
+            config__pb2.RollbackRequest.SerializeToString,
+            config__pb2.RollbackResponse.FromString,
+            options, channel_credentials,
+            insecure, call_credentials, compression, wait_for_ready, timeout, metadata)
+
+    @staticmethod
+    def WatchConfig(request,
+            target,
+            options=(),
+            channel_credentials=None,
+            call_credentials=None,
+            insecure=False,
+            compression=None,
+            wait_for_ready=None,
+            timeout=None,
+            metadata=None):
+        return grpc.experimental.unary_stream(request, target, '/pb.Config/WatchConfig',
+            config__pb2.WatchConfigRequest.SerializeToString,
+            config__pb2.ConfigEvent.FromString,
+            options, channel_credentials,
+            insecure, call_credentials, compression, wait_for_ready, timeout, metadata)
@@ -0,0 +1,185 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+
+	"github.com/spark-lence/tiga"
+	pb "github.com/spark-lence/tiga/rpc/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+var (
+	port = flag.Int("port", 50051, "The server port")
+)
+
+// WithAddress 设置服务监听的地址，例如 :50051，默认使用 -port 参数
+func WithAddress(addr string) ConfigServerOption {
+	return func(s *ConfigServer) {
+		s.addr = addr
+	}
+}
+
+// WithListener 使用已创建的 listener，优先于 WithAddress
+func WithListener(lis net.Listener) ConfigServerOption {
+	return func(s *ConfigServer) {
+		s.listener = lis
+	}
+}
+
+// Serve 启动服务并阻塞，同时注册标准的健康检查与反射服务，开启认证时健康检查不需要认证，反射服务需要认证。
+// ctx 结束或调用 Shutdown 后优雅退出并返回 nil，gRPC 或 HTTP 服务异常退出时两者都会停止并返回错误，之后可以重新 Serve
+func (s *ConfigServer) Serve(ctx context.Context) error {
+	opts, err := s.serverOptions()
+	if err != nil {
+		return err
+	}
+	s.lifeMu.Lock()
+	serving := s.server != nil
+	s.lifeMu.Unlock()
+	if serving {
+		return fmt.Errorf("config server is already serving")
+	}
+	lis := s.listener
+	if lis == nil {
+		addr := s.addr
+		if addr == "" {
+			addr = fmt.Sprintf(":%d", *port)
+		}
+		if lis, err = net.Listen("tcp", addr); err != nil {
+			return fmt.Errorf("listen on %s failed:%w", addr, err)
+		}
+	}
+	server := grpc.NewServer(opts...)
+	pb.RegisterConfigServer(server, s)
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	reflection.Register(server)
+	errCh := make(chan error, 2)
+	httpServer, err := s.serveHTTP(errCh)
+	if err != nil {
+		lis.Close()
+		return err
+	}
+	// 监听成功后才记录服务状态，Shutdown 不会作用于没有启动的服务
+	s.lifeMu.Lock()
+	if s.server != nil {
+		s.lifeMu.Unlock()
+		lis.Close()
+		if httpServer != nil {
+			httpServer.Close()
+		}
+		return fmt.Errorf("config server is already serving")
+	}
+	s.server, s.health, s.httpServer = server, healthServer, httpServer
+	s.lifeMu.Unlock()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus(pb.Config_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	go func() {
+		errCh <- server.Serve(lis)
+	}()
+	log.Printf("server listening at %v", lis.Addr())
+	select {
+	case err := <-errCh:
+		s.stop(server, healthServer, httpServer)
+		return err
+	case <-ctx.Done():
+		if err := s.Shutdown(context.Background()); err != nil {
+			return err
+		}
+		return <-errCh
+	}
+}
+
+// stop 服务异常退出时停止 gRPC 与 HTTP 服务并清除服务状态
+func (s *ConfigServer) stop(server *grpc.Server, healthServer *health.Server, httpServer *http.Server) {
+	s.lifeMu.Lock()
+	if s.server == server {
+		s.server, s.health, s.httpServer = nil, nil, nil
+	}
+	s.lifeMu.Unlock()
+	healthServer.Shutdown()
+	server.Stop()
+	if httpServer != nil {
+		httpServer.Close()
+	}
+}
+
+// Shutdown 将健康状态置为 NOT_SERVING，结束所有监听流并等待进行中的请求完成，
+// ctx 结束时强制停止
+func (s *ConfigServer) Shutdown(ctx context.Context) error {
+	s.lifeMu.Lock()
+	server, healthServer, httpServer := s.server, s.health, s.httpServer
+	s.lifeMu.Unlock()
+	if server == nil {
+		return nil
+	}
+	healthServer.Shutdown()
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+	if httpServer != nil {
+		if err := httpServer.Shutdown(ctx); err != nil {
+			httpServer.Close()
+		}
+	}
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	var err error
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		server.Stop()
+		<-stopped
+		err = ctx.Err()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, hub := range s.hubs {
+		hub.cancel()
+	}
+	for env, config := range s.configs {
+		if closeErr := config.Close(); closeErr != nil {
+			log.Printf("close settings of %s failed:%v", env, closeErr)
+		}
+	}
+	return err
+}
+
+// Start 使用 -port 参数启动服务并阻塞，失败时退出进程
+//
+// Deprecated: 使用 Serve
+func (s *ConfigServer) Start() {
+	flag.Parse()
+	if err := s.Serve(context.Background()); err != nil {
+		log.Fatalf("failed to serve: %v", err)
+	}
+}
+
+// Refresh 重新读取所有已加载环境的配置，失败的环境保留原配置
+func (s *ConfigServer) Refresh() error {
+	s.mu.RLock()
+	configs := make(map[string]*tiga.Configuration, len(s.configs))
+	for env, config := range s.configs {
+		configs[env] = config
+	}
+	s.mu.RUnlock()
+	errs := make([]error, 0)
+	for env, config := range configs {
+		if _, err := config.Reload(); err != nil {
+			errs = append(errs, fmt.Errorf("reload settings of %s failed:%w", env, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,64 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestServeStopsHTTPWhenGRPCFails(t *testing.T) {
+	httpLis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed:%v", err)
+	}
+	httpAddr := httpLis.Addr().String()
+	httpLis.Close()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed:%v", err)
+	}
+	// 关闭的 listener 使 gRPC 服务立即失败
+	lis.Close()
+	s := newTestConfigServer(t, WithListener(lis), WithHTTPAddress(httpAddr))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Serve(ctx); err == nil {
+		t.Fatal("expected serve to fail on a closed listener")
+	}
+	s.lifeMu.Lock()
+	server, health, httpServer := s.server, s.health, s.httpServer
+	s.lifeMu.Unlock()
+	if server != nil || health != nil || httpServer != nil {
+		t.Fatal("expected serving state to be cleared after failure")
+	}
+	// HTTP 服务随 gRPC 一起停止，端口在服务协程退出后释放
+	for {
+		again, err := net.Listen("tcp", httpAddr)
+		if err == nil {
+			again.Close()
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("http server is still listening:%v", err)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	lis, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed:%v", err)
+	}
+	s.listener = lis
+	serveCtx, stop := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Serve(serveCtx)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	stop()
+	if err := <-done; err != nil {
+		t.Fatalf("expected serve to restart after failure, got %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package rpc
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SettingsStore 持久化 SetConfig 与 Rollback 的写入。
+// Save 返回后 Configuration.Reload 需要能读到新值，value 为 nil 表示删除该key
+type SettingsStore interface {
+	Save(env string, key string, value interface{}) error
+}
+
+// FileSettingsStore 将配置写入配置目录下的 settings.<env>.yaml，保留文件中的注释
+type FileSettingsStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+func NewFileSettingsStore(dir string) *FileSettingsStore {
+	return &FileSettingsStore{dir: dir}
+}
+
+func (f *FileSettingsStore) Save(env string, key string, value interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	path := filepath.Join(f.dir, fmt.Sprintf("settings.%s.yaml", env))
+	doc := &yaml.Node{Kind: yaml.DocumentNode}
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read settings file %s failed:%w", path, err)
+		}
+		if err := yaml.Unmarshal(data, doc); err != nil {
+			return fmt.Errorf("parse settings file %s failed:%w", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if len(doc.Content) == 0 {
+		doc.Kind = yaml.DocumentNode
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("settings file %s is not a mapping", path)
+	}
+	// 文件中显式写了 <env>: 的，写到该节点下
+	if node := lookupNode(root, env); node != nil && node.Kind == yaml.MappingNode {
+		root = node
+	}
+	parts := strings.Split(strings.TrimPrefix(strings.ToLower(key), env+"."), ".")
+	if value == nil {
+		deleteNode(root, parts)
+	} else if err := setNode(root, parts, value); err != nil {
+		return fmt.Errorf("set %s in %s failed:%w", key, path, err)
+	}
+	buf := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, buf.Bytes(), mode)
+}
+
+// lookupNode 在 mapping 节点中查找key，忽略大小写
+func lookupNode(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if strings.EqualFold(mapping.Content[i].Value, key) {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func setNode(mapping *yaml.Node, parts []string, value interface{}) error {
+	node := lookupNode(mapping, parts[0])
+	if len(parts) == 1 {
+		valueNode := &yaml.Node{}
+		if err := valueNode.Encode(value); err != nil {
+			return err
+		}
+		if node == nil {
+			mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: parts[0]}, valueNode)
+			return nil
+		}
+		valueNode.HeadComment, valueNode.LineComment, valueNode.FootComment = node.HeadComment, node.LineComment, node.FootComment
+		*node = *valueNode
+		return nil
+	}
+	if node == nil || node.Kind != yaml.MappingNode {
+		child := &yaml.Node{Kind: yaml.MappingNode}
+		if node == nil {
+			mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: parts[0]}, child)
+		} else {
+			*node = *child
+		}
+		node = child
+	}
+	return setNode(node, parts[1:], value)
+}
+
+// deleteNode 删除key，并清理删除后为空的父节点
+func deleteNode(mapping *yaml.Node, parts []string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if !strings.EqualFold(mapping.Content[i].Value, parts[0]) {
+			continue
+		}
+		if len(parts) > 1 {
+			child := mapping.Content[i+1]
+			if child.Kind != yaml.MappingNode {
+				return
+			}
+			deleteNode(child, parts[1:])
+			if len(child.Content) > 0 {
+				return
+			}
+		}
+		mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+		return
+	}
+}
+
+// writeFileAtomic 先写临时文件再重命名，避免重载时读到写了一半的文件
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
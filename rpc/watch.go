@@ -0,0 +1,247 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/spark-lence/tiga"
+	pb "github.com/spark-lence/tiga/rpc/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// watchHistorySize 每个环境保留的最近变更数，用于断线重连后续传
+	watchHistorySize = 1024
+	// watchBufferSize 单个监听者未发送的变更上限，超过后断开该监听者
+	watchBufferSize = 256
+)
+
+// hubEvent 变更事件，secret 标记敏感配置，推送时根据调用方权限脱敏
+type hubEvent struct {
+	*pb.ConfigEvent
+	secret bool
+}
+
+type configWatcher struct {
+	prefix string
+	events chan *hubEvent
+	// overflow 监听者消费过慢，事件被丢弃
+	overflow bool
+}
+
+// configHub 记录单个环境的配置变更，并分发给该环境的所有监听者
+type configHub struct {
+	env    string
+	config *tiga.Configuration
+
+	mu       sync.Mutex
+	history  []*hubEvent
+	floor    uint64
+	revision uint64
+	watchers map[*configWatcher]struct{}
+	cancel   func()
+}
+
+func newConfigHub(env string, config *tiga.Configuration) *configHub {
+	hub := &configHub{
+		env:      env,
+		config:   config,
+		watchers: make(map[*configWatcher]struct{}),
+	}
+	hub.mu.Lock()
+	hub.cancel = config.SubscribeChanges(hub.publish)
+	hub.revision = config.Revision()
+	hub.floor = hub.revision
+	hub.mu.Unlock()
+	return hub
+}
+
+// eventKey 将完整的配置key转换为客户端使用的key，其他环境的key返回空
+func (h *configHub) eventKey(key string) string {
+	if strings.HasPrefix(key, h.env+".") {
+		return strings.TrimPrefix(key, h.env+".")
+	}
+	if strings.HasPrefix(key, "common.") {
+		return key
+	}
+	return ""
+}
+
+func (h *configHub) encode(key string, val interface{}) []byte {
+	if val == nil {
+		return nil
+	}
+	resolved, err := h.config.ResolveSecrets(val)
+	if err != nil {
+		tiga.GetLogger("rpc").Errorf("resolve secret %s failed:%v", key, err)
+		return nil
+	}
+	data, err := encodeValue(key, resolved)
+	if err != nil {
+		tiga.GetLogger("rpc").Errorf("%v", err)
+		return nil
+	}
+	return data
+}
+
+func (h *configHub) publish(revision uint64, changes []tiga.ConfigChange) {
+	events := make([]*hubEvent, 0, len(changes))
+	for _, change := range changes {
+		key := h.eventKey(change.Key)
+		if key == "" {
+			continue
+		}
+		events = append(events, &hubEvent{
+			ConfigEvent: &pb.ConfigEvent{
+				Key:      key,
+				OldValue: h.encode(key, change.Old),
+				NewValue: h.encode(key, change.New),
+				Revision: revision,
+			},
+			secret: isSecret(key, change.Old) || isSecret(key, change.New),
+		})
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if revision <= h.revision {
+		return
+	}
+	h.revision = revision
+	h.history = append(h.history, events...)
+	if over := len(h.history) - watchHistorySize; over > 0 {
+		// 同一版本的变更要么全部保留要么全部丢弃，保证续传时不会只收到部分变更
+		for over < len(h.history) && h.history[over].Revision == h.history[over-1].Revision {
+			over++
+		}
+		h.floor = h.history[over-1].Revision
+		h.history = append([]*hubEvent(nil), h.history[over:]...)
+	}
+	for watcher := range h.watchers {
+		h.send(watcher, events)
+	}
+}
+
+// send 调用方必须持有 mu
+func (h *configHub) send(watcher *configWatcher, events []*hubEvent) {
+	for _, event := range events {
+		if watcher.overflow || !matchKey(watcher.prefix, event.Key) {
+			continue
+		}
+		select {
+		case watcher.events <- event:
+		default:
+			watcher.overflow = true
+			delete(h.watchers, watcher)
+			close(watcher.events)
+		}
+	}
+}
+
+// currentRevision 已经分发的最新版本号
+func (h *configHub) currentRevision() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.revision
+}
+
+// watch 注册监听者，fromRevision 大于0时先补发该版本之后的变更
+func (h *configHub) watch(prefix string, fromRevision uint64) (*configWatcher, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	watcher := &configWatcher{prefix: prefix, events: make(chan *hubEvent, watchBufferSize)}
+	if fromRevision > 0 {
+		if fromRevision < h.floor || fromRevision > h.revision {
+			return nil, status.Errorf(codes.OutOfRange, "revision %d of %s is not available, current revision is %d, oldest is %d", fromRevision, h.env, h.revision, h.floor)
+		}
+		for _, event := range h.history {
+			if event.Revision <= fromRevision || !matchKey(prefix, event.Key) {
+				continue
+			}
+			if len(watcher.events) == cap(watcher.events) {
+				return nil, status.Errorf(codes.OutOfRange, "too many changes since revision %d of %s", fromRevision, h.env)
+			}
+			watcher.events <- event
+		}
+	}
+	h.watchers[watcher] = struct{}{}
+	return watcher, nil
+}
+
+func (h *configHub) unwatch(watcher *configWatcher) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.watchers[watcher]; ok {
+		delete(h.watchers, watcher)
+		close(watcher.events)
+	}
+}
+
+// matchKey 判断key是否落在监听的前缀下
+func matchKey(prefix string, key string) bool {
+	return prefix == "" || key == prefix || strings.HasPrefix(key, strings.TrimSuffix(prefix, ".")+".")
+}
+
+// redactEvent 调用方没有读权限的变更返回 nil，没有 PermSecret 权限的敏感配置值替换为 ******
+func (s *ConfigServer) redactEvent(ctx context.Context, env string, event *hubEvent) *pb.ConfigEvent {
+	if !s.allowed(ctx, env, event.Key, PermRead) {
+		return nil
+	}
+	if !event.secret || s.allowed(ctx, env, event.Key, PermSecret) {
+		return event.ConfigEvent
+	}
+	redacted := &pb.ConfigEvent{Key: event.Key, Revision: event.Revision}
+	masked, _ := encodeValue(event.Key, redactedValue)
+	if len(event.OldValue) > 0 {
+		redacted.OldValue = masked
+	}
+	if len(event.NewValue) > 0 {
+		redacted.NewValue = masked
+	}
+	return redacted
+}
+
+// WatchConfig 推送环境下以 prefix 开头的配置变更，客户端重连时通过 FromRevision 续传，
+// 调用方没有读权限的key不会推送
+func (s *ConfigServer) WatchConfig(in *pb.WatchConfigRequest, stream pb.Config_WatchConfigServer) error {
+	return s.watch(stream.Context(), in, nil, stream.Send)
+}
+
+// watch WatchConfig 与HTTP网关共用的监听逻辑，注册成功后调用 ready，send 返回错误时结束监听
+func (s *ConfigServer) watch(ctx context.Context, in *pb.WatchConfigRequest, ready func(), send func(*pb.ConfigEvent) error) error {
+	hub, err := s.hub(in.Env)
+	if err != nil {
+		return err
+	}
+	if !s.allowedEnv(ctx, hub.env) {
+		return status.Errorf(codes.PermissionDenied, "%s has no read permission on %s", caller(ctx), hub.env)
+	}
+	watcher, err := hub.watch(in.Prefix, in.FromRevision)
+	if err != nil {
+		return err
+	}
+	defer hub.unwatch(watcher)
+	if ready != nil {
+		ready()
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.done:
+			return status.Errorf(codes.Unavailable, "config server is shutting down")
+		case event, ok := <-watcher.events:
+			if !ok {
+				return status.Errorf(codes.ResourceExhausted, "watcher of %s is too slow, resume from the last received revision", hub.env)
+			}
+			redacted := s.redactEvent(ctx, hub.env, event)
+			if redacted == nil {
+				continue
+			}
+			if err := send(redacted); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -3,9 +3,11 @@ package tiga
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
-	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cast"
 	"github.com/spf13/viper"
 )
 
@@ -14,15 +16,33 @@ type Settings interface {
 	GetValue(key string) (interface{}, error)
 }
 
+// Configuration 配置的只读视图，底层数据以快照形式保存在 configStore 中，
+// 重载时整体替换快照，读操作不会看到只应用了一半的配置
 type Configuration struct {
 	// Log *Logger `ymal:"log"`
-	*viper.Viper
-	env string
+	env   string
+	store *configStore
 }
 
 // var onceConfig sync.Once
 // var config *Configuration = nil
 
+// DefaultEnv 未指定环境且没有设置 RUN_MODE 时使用的环境
+const DefaultEnv = "dev"
+
+// ResolveEnv 确定 Configuration 的环境，优先级为:
+// 显式传入的 env > 环境变量 RUN_MODE > DefaultEnv。
+// 只在创建 Configuration 时调用一次，之后环境不可变，RUN_MODE 的变化不会影响已创建的配置
+func ResolveEnv(env string) string {
+	if env != "" {
+		return env
+	}
+	if mode, ok := os.LookupEnv("RUN_MODE"); ok && mode != "" {
+		return mode
+	}
+	return DefaultEnv
+}
+
 func NewConfig(env string) *Configuration {
 	// onceConfig.Do(func() {
 	// 	config = &Configuration{
@@ -30,24 +50,28 @@ func NewConfig(env string) *Configuration {
 	// 		env,
 	// 	}
 	// })
+	env = ResolveEnv(env)
 	config := &Configuration{
-		viper.New(),
-		env,
+		env:   env,
+		store: newConfigStore("", env, newSettingsOptions()),
 	}
 	return config
 
 }
-func (c Configuration) GetValue(key string) (interface{}, error) {
-	if !strings.Contains(key, c.env) {
-		key = fmt.Sprintf("%s.%s", c.env, key)
+
+// WithEnv 返回指定环境的配置视图，与当前配置共享底层数据、订阅与重载，
+// 视图读取的 common.* 与环境变量层按 env 计算。订阅是否触发仍以创建配置时的环境的变更为准
+func (c Configuration) WithEnv(env string) *Configuration {
+	return &Configuration{
+		env:   env,
+		store: c.store,
 	}
+}
+func (c Configuration) GetValue(key string) (interface{}, error) {
 	value := c.Get(key)
 	return value, nil
 }
 func (c Configuration) GetString(key string) string {
-	if !strings.Contains(key, c.env)&&!strings.HasPrefix(key,"common") {
-		key = fmt.Sprintf("%s.%s", c.env, key)
-	}
 	value := c.Get(key)
 	if val,ok:=value.(string);ok&&val!=""{
 		return val
@@ -55,9 +79,6 @@ func (c Configuration) GetString(key string) string {
 	return ""
 }
 func (c Configuration) GetStrings(key string) []string {
-	if !strings.Contains(key, c.env) {
-		key = fmt.Sprintf("%s.%s", c.env, key)
-	}
 	values := c.GetStringSlice(key)
 	return values
 }
@@ -67,11 +88,12 @@ func (c Configuration)GetStringSlice(key string) []string {
 	if !strings.HasPrefix(key, c.env)&&!strings.HasPrefix(key,"common") {
 		key = fmt.Sprintf("%s.%s", c.env, key)
 	}
-	v:= c.Viper.GetStringSlice(key)
+	v:= c.viper().GetStringSlice(key)
 	if len(v)==0{
-		return c.Viper.GetStringSlice(originKey)
+		key = originKey
+		v = c.viper().GetStringSlice(originKey)
 	}
-	return v
+	return cast.ToStringSlice(c.resolve(key, cast.ToSlice(v)))
 }
 func (c Configuration)GetStringMap(key string)map[string]any{
 	key =strings.TrimPrefix(key, fmt.Sprintf("%s.",c.env))
@@ -79,16 +101,14 @@ func (c Configuration)GetStringMap(key string)map[string]any{
 	if !strings.HasPrefix(key, c.env)&&!strings.HasPrefix(key,"common") {
 		key = fmt.Sprintf("%s.%s", c.env, key)
 	}
-	v:= c.Viper.GetStringMap(key)
+	v:= c.viper().GetStringMap(key)
 	if len(v)==0{
-		return c.Viper.GetStringMap(originKey)
+		key = originKey
+		v = c.viper().GetStringMap(originKey)
 	}
-	return v
+	return cast.ToStringMap(c.resolve(key, v))
 }
 func (c Configuration) GetInt(key string) int {
-	if !strings.Contains(key, c.env) {
-		key = fmt.Sprintf("%s.%s", c.env, key)
-	}
 	value := c.Get(key)
 	switch v := value.(type) {
 	case int:
@@ -97,64 +117,129 @@ func (c Configuration) GetInt(key string) int {
 		return int(v)
 	case float64:
 		return int(v)
+	case string:
+		// 环境变量覆盖的值均为字符串
+		i, _ := strconv.Atoi(strings.TrimSpace(v))
+		return i
 	default:
-		return 0
+		return cast.ToInt(v)
 	}
 
 }
 func (c Configuration) UnmarshalKey(key string, rawVal any, opts ...viper.DecoderConfigOption) error {
 
-	return c.Viper.UnmarshalKey(key, rawVal, opts...)
+	return c.viper().UnmarshalKey(key, rawVal, opts...)
 }
+// GetEnv 返回创建时确定的环境，见 ResolveEnv
 func (c Configuration) GetEnv() string {
-	env, ok := os.LookupEnv("RUN_MODE")
-	if !ok {
-		env = c.env
-	}
-	return env
-}
-func (c Configuration) SetEnv(env string) {
-	c.env = env
+	return c.env
 }
 func (c Configuration) GetConfigByEnv(env string, key string) interface{} {
-	return c.Get(fmt.Sprintf("%s.%s", env, key))
+	return c.WithEnv(env).Get(key)
 }
+// Get 获取配置，优先查找 <env>.key，其次查找 key，值中的密文引用会被解析
 func (c Configuration)Get(key string) interface{} {
+	key, v := c.getRaw(key)
+	return c.resolve(key, v)
+
+}
+
+// getRaw 获取未解析密文引用的原始值以及实际命中的key
+func (c Configuration) getRaw(key string) (string, interface{}) {
 	key =strings.TrimPrefix(key, fmt.Sprintf("%s.",c.env))
 	originKey:=key
 	if !strings.HasPrefix(key, c.env)&&!strings.HasPrefix(key,"common") {
 		key = fmt.Sprintf("%s.%s", c.env, key)
 	}
-	v:= c.Viper.Get(key)
+	v:= c.viper().Get(key)
 	if v==nil{
-		return c.Viper.Get(originKey)
-	}
-	return v
-
-}
-func (c Configuration) SetConfig(key string, val string, env string) {
-	c.Set(fmt.Sprintf("%s.%s", env, key), val)
-}
-func (c Configuration) load(dir string) bool {
-	c.AddConfigPath(dir)
-	c.SetConfigName("settings")
-	c.SetConfigType("yaml")
-	readErr := c.ReadInConfig()
-	if readErr != nil {
-		panic(readErr)
-	}
-	err := c.Unmarshal(c)
-	return err == nil
-}
-func InitSettings(env string, settingDir string) *Configuration {
-	config := NewConfig(env)
-	// Config.load(wd)
-	config.load(settingDir)
-	config.WatchConfig()
-	config.OnConfigChange(func(e fsnotify.Event) {
-		fmt.Println("Config file changed:", e.Name)
-		config.load(settingDir)
-	})
+		return originKey, c.viper().Get(originKey)
+	}
+	return key, v
+}
+func (c Configuration) SetConfig(key string, val string, env string) error {
+	return c.setOverrides(map[string]interface{}{strings.ToLower(fmt.Sprintf("%s.%s", env, key)): val})
+}
+
+// Set 等价于 Override
+func (c Configuration) Set(key string, value interface{}) error {
+	return c.Override(key, value)
+}
+func (c Configuration) GetBool(key string) bool {
+	return cast.ToBool(c.Get(key))
+}
+func (c Configuration) GetInt64(key string) int64 {
+	return cast.ToInt64(c.Get(key))
+}
+func (c Configuration) GetFloat64(key string) float64 {
+	return cast.ToFloat64(c.Get(key))
+}
+func (c Configuration) GetDuration(key string) time.Duration {
+	return cast.ToDuration(c.Get(key))
+}
+func (c Configuration) GetStringMapString(key string) map[string]string {
+	return cast.ToStringMapString(c.Get(key))
+}
+func (c Configuration) IsSet(key string) bool {
+	return c.Get(key) != nil
+}
+func (c Configuration) AllKeys() []string {
+	return c.viper().AllKeys()
+}
+func (c Configuration) AllSettings() map[string]interface{} {
+	return c.viper().AllSettings()
+}
+func (c Configuration) Unmarshal(rawVal any, opts ...viper.DecoderConfigOption) error {
+	return c.viper().Unmarshal(rawVal, opts...)
+}
+func (c Configuration) snapshot() *settingsSnapshot {
+	if c.store == nil {
+		return emptySnapshot
+	}
+	return c.store.view(c.store.snapshot.Load(), c.env)
+}
+
+// viper 当前快照，只能用于读取
+func (c Configuration) viper() *viper.Viper {
+	return c.snapshot().v
+}
+
+// LoadSettings 按 settings.yaml < settings.<env>.yaml < 远程配置 < settings.local.yaml < 环境变量 < 显式覆盖
+// 的优先级加载 settingDir 下的分层配置
+func LoadSettings(env string, settingDir string, opts ...SettingsOption) (*Configuration, error) {
+	o := newSettingsOptions(opts...)
+	env = ResolveEnv(env)
+	config := &Configuration{
+		env:   env,
+		store: newConfigStore(settingDir, env, o),
+	}
+	for k, v := range o.overrides {
+		config.store.overrides[config.normalizeKey(k)] = v
+	}
+	for _, validator := range o.validators {
+		config.store.validators = append(config.store.validators, validator)
+	}
+	if o.remote != nil {
+		if err := config.store.loadRemote(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := config.Reload(); err != nil {
+		return nil, err
+	}
+	if o.remote != nil {
+		config.store.watchRemote()
+	}
+	return config, nil
+}
+func InitSettings(env string, settingDir string, opts ...SettingsOption) *Configuration {
+	config, err := LoadSettings(env, settingDir, opts...)
+	if err != nil {
+		panic(err)
+	}
+	if err := config.WatchConfig(); err != nil {
+		GetLogger("settings").Errorf("watch settings failed:%v", err)
+	}
 	return config
 
 }
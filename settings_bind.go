@@ -0,0 +1,194 @@
+package tiga
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+)
+
+// ConfigFieldError 单个配置项绑定失败的原因
+type ConfigFieldError struct {
+	Key    string
+	Reason string
+}
+
+// ConfigBindError 汇总 Bind 过程中所有缺失或非法的配置项
+type ConfigBindError struct {
+	Prefix string
+	Fields []ConfigFieldError
+}
+
+func (e *ConfigBindError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, fmt.Sprintf("%s %s", f.Key, f.Reason))
+	}
+	return fmt.Sprintf("bind config %s failed: %s", e.Prefix, strings.Join(msgs, "; "))
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Bind 将 prefix 下的配置绑定到结构体，字段通过以下标签描述:
+//
+//	config:"host"             配置key，缺省为字段名小写
+//	default:"3306"            配置缺失时使用的默认值
+//	validate:"required,min=1" 校验规则，见 validateValue
+//
+// key 的查找顺序为 <env>.<prefix>.<key>、common.<prefix>.<key>、<prefix>.<key>，
+// 所有缺失或非法的配置项会汇总在一个 *ConfigBindError 中返回
+func (c Configuration) Bind(prefix string, out interface{}) error {
+	val := reflect.ValueOf(out)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind config %s failed: expected a pointer to struct but got %T", prefix, out)
+	}
+	bindErr := &ConfigBindError{Prefix: prefix}
+	c.bindStruct(prefix, val.Elem(), bindErr)
+	if len(bindErr.Fields) > 0 {
+		return bindErr
+	}
+	return nil
+}
+
+// lookup 按 env、common、原始key 的顺序查找配置，并解析其中的密文引用
+func (c Configuration) lookup(key string) (interface{}, bool, error) {
+	key = strings.TrimPrefix(key, fmt.Sprintf("%s.", c.env))
+	candidates := []string{key}
+	if !strings.HasPrefix(key, "common.") {
+		candidates = []string{fmt.Sprintf("%s.%s", c.env, key), fmt.Sprintf("common.%s", key), key}
+	}
+	var val interface{}
+	for _, candidate := range candidates {
+		if val = c.viper().Get(candidate); val != nil {
+			break
+		}
+	}
+	if val == nil {
+		return nil, false, nil
+	}
+	resolved, err := c.resolveE(val)
+	return resolved, true, err
+}
+
+func (c Configuration) bindStruct(prefix string, val reflect.Value, bindErr *ConfigBindError) {
+	typ := val.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := field.Tag.Get("config")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		key := name
+		if prefix != "" {
+			key = fmt.Sprintf("%s.%s", prefix, name)
+		}
+		fieldVal := val.Field(i)
+		if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Time{}) {
+			c.bindStruct(key, fieldVal, bindErr)
+			continue
+		}
+		raw, ok, err := c.lookup(key)
+		if err != nil {
+			bindErr.Fields = append(bindErr.Fields, ConfigFieldError{Key: key, Reason: err.Error()})
+			continue
+		}
+		if !ok {
+			if def, has := field.Tag.Lookup("default"); has {
+				raw, ok = def, true
+			}
+		}
+		rules := parseValidateTag(field.Tag.Get("validate"))
+		if !ok {
+			if hasValidateRule(rules, "required") {
+				bindErr.Fields = append(bindErr.Fields, ConfigFieldError{Key: key, Reason: "is missing"})
+			}
+			continue
+		}
+		if err := setFieldValue(fieldVal, raw); err != nil {
+			bindErr.Fields = append(bindErr.Fields, ConfigFieldError{Key: key, Reason: err.Error()})
+			continue
+		}
+		for _, reason := range validateValue(fieldVal, rules) {
+			bindErr.Fields = append(bindErr.Fields, ConfigFieldError{Key: key, Reason: reason})
+		}
+	}
+}
+
+// setFieldValue 将配置值转换为字段类型并赋值
+func setFieldValue(field reflect.Value, raw interface{}) error {
+	var (
+		val interface{}
+		err error
+	)
+	if field.Type() == durationType {
+		val, err = cast.ToDurationE(raw)
+		if err == nil {
+			field.SetInt(int64(val.(time.Duration)))
+		}
+		return wrapCastError(field, raw, err)
+	}
+	switch field.Kind() {
+	case reflect.String:
+		val, err = cast.ToStringE(raw)
+		if err == nil {
+			field.SetString(val.(string))
+		}
+	case reflect.Bool:
+		val, err = cast.ToBoolE(raw)
+		if err == nil {
+			field.SetBool(val.(bool))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		val, err = cast.ToInt64E(raw)
+		if err == nil {
+			field.SetInt(val.(int64))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		val, err = cast.ToUint64E(raw)
+		if err == nil {
+			field.SetUint(val.(uint64))
+		}
+	case reflect.Float32, reflect.Float64:
+		val, err = cast.ToFloat64E(raw)
+		if err == nil {
+			field.SetFloat(val.(float64))
+		}
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported field type %s", field.Type())
+		}
+		if str, ok := raw.(string); ok {
+			raw = strings.Split(str, ",")
+		}
+		val, err = cast.ToStringSliceE(raw)
+		if err == nil {
+			field.Set(reflect.ValueOf(val))
+		}
+	case reflect.Map:
+		if field.Type().Key().Kind() != reflect.String || field.Type().Elem().Kind() != reflect.Interface {
+			return fmt.Errorf("unsupported field type %s", field.Type())
+		}
+		val, err = cast.ToStringMapE(raw)
+		if err == nil {
+			field.Set(reflect.ValueOf(val))
+		}
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return wrapCastError(field, raw, err)
+}
+
+func wrapCastError(field reflect.Value, raw interface{}, err error) error {
+	if err != nil {
+		return fmt.Errorf("cannot convert %v (%T) to %s", raw, raw, field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package tiga
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// EtcdKV EtcdSource 依赖的 etcd 操作，EtcdDao 实现了该接口
+type EtcdKV interface {
+	GetResponse(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error)
+	Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan
+}
+
+// EtcdSource 基于 etcd 的远程配置源，
+// /<app>/<env>/mysql/host 映射为 <env>.mysql.host，/<app>/common/x 映射为 common.x
+type EtcdSource struct {
+	kv     EtcdKV
+	prefix string
+
+	mu       sync.Mutex
+	values   map[string]interface{}
+	revision int64
+}
+
+// etcdRetryInterval etcd 连接断开后重新监听的最大间隔
+const etcdRetryInterval = 30 * time.Second
+
+func NewEtcdSource(kv EtcdKV, app string) *EtcdSource {
+	return &EtcdSource{
+		kv:     kv,
+		prefix: fmt.Sprintf("/%s/", strings.Trim(app, "/")),
+		values: make(map[string]interface{}),
+	}
+}
+
+// configKey 将 etcd key 转换为配置key，前缀之外的key返回空
+func (e *EtcdSource) configKey(key string) string {
+	if !strings.HasPrefix(key, e.prefix) {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(key, e.prefix), "/"), "/")
+	if len(parts) < 2 {
+		return ""
+	}
+	return strings.ToLower(strings.Join(parts, "."))
+}
+
+func (e *EtcdSource) snapshot() map[string]interface{} {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return copySettings(e.values)
+}
+
+func (e *EtcdSource) Load(ctx context.Context) (map[string]interface{}, error) {
+	resp, err := e.kv.GetResponse(ctx, e.prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("get etcd prefix %s failed:%w", e.prefix, err)
+	}
+	values := make(map[string]interface{}, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		if key := e.configKey(string(kv.Key)); key != "" {
+			values[key] = string(kv.Value)
+		}
+	}
+	e.mu.Lock()
+	e.values = values
+	// 使用响应头中的集群版本，而不是返回的 key 中最大的 ModRevision，
+	// 前缀为空或者 key 很久没有修改时后者可能已经被压缩，监听会一直失败
+	e.revision = resp.Header.GetRevision()
+	e.mu.Unlock()
+	return copySettings(values), nil
+}
+
+// Watch 从最近一次 Load 的版本之后开始监听，连接中断或版本被压缩时重新 Load 后继续监听
+func (e *EtcdSource) Watch(ctx context.Context, onChange func(values map[string]interface{})) error {
+	retry := time.Second
+	reload := false
+	for {
+		if reload {
+			if _, err := e.Load(ctx); err != nil {
+				GetLogger("settings").Warnf("reload etcd settings failed:%v", err)
+				if !sleepContext(ctx, retry) {
+					return ctx.Err()
+				}
+				retry = minDuration(retry*2, etcdRetryInterval)
+				continue
+			}
+			onChange(e.snapshot())
+		}
+		e.mu.Lock()
+		revision := e.revision
+		e.mu.Unlock()
+		watchCtx := clientv3.WithRequireLeader(ctx)
+		for resp := range e.kv.Watch(watchCtx, e.prefix, clientv3.WithPrefix(), clientv3.WithRev(revision+1)) {
+			if err := resp.Err(); err != nil {
+				GetLogger("settings").Warnf("watch etcd prefix %s failed:%v", e.prefix, err)
+				break
+			}
+			if e.apply(resp.Events, resp.Header.Revision) {
+				onChange(e.snapshot())
+			}
+			retry = time.Second
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		reload = true
+		if !sleepContext(ctx, retry) {
+			return ctx.Err()
+		}
+		retry = minDuration(retry*2, etcdRetryInterval)
+	}
+}
+
+// apply 将 watch 事件应用到本地副本，返回是否有配置变化
+func (e *EtcdSource) apply(events []*clientv3.Event, revision int64) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	changed := false
+	for _, event := range events {
+		key := e.configKey(string(event.Kv.Key))
+		if key == "" {
+			continue
+		}
+		switch event.Type {
+		case clientv3.EventTypePut:
+			e.values[key] = string(event.Kv.Value)
+		case clientv3.EventTypeDelete:
+			delete(e.values, key)
+		}
+		changed = true
+	}
+	if revision > e.revision {
+		e.revision = revision
+	}
+	return changed
+}
+
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func minDuration(a time.Duration, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
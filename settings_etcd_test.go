@@ -0,0 +1,96 @@
+package tiga
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// revisionKV 在 etcd mock 之上补充响应头版本号与 watch，mock 只实现了 KV 服务且响应头为空
+type revisionKV struct {
+	*EtcdDao
+	revision int64
+	watches  chan int64
+	compact  bool
+}
+
+func (kv *revisionKV) GetResponse(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	resp, err := kv.EtcdDao.GetResponse(ctx, key, opts...)
+	if err != nil {
+		return nil, err
+	}
+	resp.Header = &pb.ResponseHeader{Revision: kv.revision}
+	return resp, nil
+}
+
+func (kv *revisionKV) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	kv.watches <- clientv3.OpGet(key, opts...).Rev()
+	ch := make(chan clientv3.WatchResponse, 1)
+	if kv.compact {
+		// 第一次监听时版本已被压缩，重新加载时集群版本已经前进
+		kv.compact = false
+		kv.revision = 200
+		ch <- clientv3.WatchResponse{CompactRevision: 150}
+		close(ch)
+		return ch
+	}
+	ch <- clientv3.WatchResponse{
+		Header: pb.ResponseHeader{Revision: 201},
+		Events: []*clientv3.Event{{
+			Type: clientv3.EventTypePut,
+			Kv:   &mvccpb.KeyValue{Key: []byte("/app/dev/mysql/host"), Value: []byte("db")},
+		}},
+	}
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+	return ch
+}
+
+func TestEtcdSourceWatchFromHeaderRevision(t *testing.T) {
+	dao := NewEtcdMockDao()
+	defer dao.Close()
+	kv := &revisionKV{EtcdDao: dao, revision: 100, watches: make(chan int64, 2), compact: true}
+	source := NewEtcdSource(kv, "app")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	values, err := source.Load(ctx)
+	if err != nil {
+		t.Fatalf("load failed:%v", err)
+	}
+	if len(values) != 0 {
+		t.Fatalf("expected empty prefix, got %v", values)
+	}
+	changes := make(chan map[string]interface{}, 4)
+	done := make(chan error, 1)
+	go func() {
+		done <- source.Watch(ctx, func(values map[string]interface{}) {
+			changes <- values
+		})
+	}()
+	// 前缀为空时从响应头版本之后开始监听，而不是从版本1重放
+	if rev := <-kv.watches; rev != 101 {
+		t.Fatalf("expected first watch from revision 101, got %d", rev)
+	}
+	// 版本被压缩后重新加载，从新的响应头版本之后监听，不会重复监听已压缩的版本
+	if rev := <-kv.watches; rev != 201 {
+		t.Fatalf("expected watch after compaction from revision 201, got %d", rev)
+	}
+	for {
+		select {
+		case values := <-changes:
+			if values["dev.mysql.host"] == "db" {
+				cancel()
+				<-done
+				return
+			}
+		case <-ctx.Done():
+			t.Fatal("watch event was not applied")
+		}
+	}
+}
@@ -0,0 +1,395 @@
+package tiga
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// ConfigLayer 配置层，优先级从低到高依次为
+// base < env_file < remote < local < env_var < override
+type ConfigLayer string
+
+const (
+	// LayerBase 基础配置文件 settings.yaml
+	LayerBase ConfigLayer = "base"
+	// LayerEnvFile 环境配置文件 settings.<env>.yaml，顶层key默认挂在<env>下，所有环境的文件都会被加载
+	LayerEnvFile ConfigLayer = "env_file"
+	// LayerRemote 远程配置源，例如 etcd
+	LayerRemote ConfigLayer = "remote"
+	// LayerLocal 本地覆盖文件 settings.local.yaml，不纳入版本管理
+	LayerLocal ConfigLayer = "local"
+	// LayerEnvVar 以 TIGA_ 为前缀的环境变量，只作用于创建配置时确定的环境
+	LayerEnvVar ConfigLayer = "env_var"
+	// LayerOverride 代码中显式设置的值
+	LayerOverride ConfigLayer = "override"
+)
+
+// DefaultEnvPrefix 环境变量默认前缀
+const DefaultEnvPrefix = "TIGA"
+
+var layerOrder = []ConfigLayer{LayerBase, LayerEnvFile, LayerRemote, LayerLocal, LayerEnvVar, LayerOverride}
+
+type SettingsOption func(*settingsOptions)
+
+type settingsOptions struct {
+	envPrefix   string
+	localName   string
+	overrides   map[string]interface{}
+	validators  []ConfigValidator
+	remote      RemoteSource
+	remoteCache string
+}
+
+// WithEnvPrefix 设置环境变量前缀，默认为 TIGA
+func WithEnvPrefix(prefix string) SettingsOption {
+	return func(o *settingsOptions) {
+		o.envPrefix = strings.ToUpper(strings.TrimSuffix(prefix, "_"))
+	}
+}
+
+// WithLocalSettings 设置本地覆盖文件名(不含扩展名)，默认为 settings.local
+func WithLocalSettings(name string) SettingsOption {
+	return func(o *settingsOptions) {
+		o.localName = name
+	}
+}
+
+// WithOverrides 显式覆盖的配置，key 规则与 Configuration.Get 一致
+func WithOverrides(overrides map[string]interface{}) SettingsOption {
+	return func(o *settingsOptions) {
+		for k, v := range overrides {
+			o.overrides[k] = v
+		}
+	}
+}
+
+// WithValidator 注册配置校验函数，首次加载以及之后的每次重载都需要通过校验
+func WithValidator(validator ConfigValidator) SettingsOption {
+	return func(o *settingsOptions) {
+		o.validators = append(o.validators, validator)
+	}
+}
+
+func newSettingsOptions(opts ...SettingsOption) settingsOptions {
+	o := settingsOptions{
+		envPrefix: DefaultEnvPrefix,
+		localName: "settings.local",
+		overrides: make(map[string]interface{}),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// settingsSnapshot 某一时刻完整的配置，构建完成后只读，
+// 任何变更都通过构建新的快照并原子替换完成
+type settingsSnapshot struct {
+	v        *viper.Viper
+	layers   map[ConfigLayer]map[string]interface{}
+	flat     map[string]interface{}
+	revision uint64
+	// secrets 已解析的密文引用缓存
+	secrets sync.Map
+	// commons 各环境配置文件中的 common.*，key 为环境名，用于生成其他环境的视图
+	commons map[string]map[string]interface{}
+	// views WithEnv 视图使用的快照缓存，key 为环境名
+	views sync.Map
+}
+
+var emptySnapshot = &settingsSnapshot{
+	v:      viper.New(),
+	layers: make(map[ConfigLayer]map[string]interface{}),
+	flat:   make(map[string]interface{}),
+}
+
+// newSettingsSnapshot 按优先级依次合并各层配置
+func newSettingsSnapshot(layers map[ConfigLayer]map[string]interface{}) (*settingsSnapshot, error) {
+	v := viper.New()
+	for _, layer := range layerOrder {
+		if len(layers[layer]) == 0 {
+			continue
+		}
+		if err := v.MergeConfigMap(expandSettings(layers[layer])); err != nil {
+			return nil, fmt.Errorf("merge %s settings failed:%w", layer, err)
+		}
+	}
+	return &settingsSnapshot{
+		v:      v,
+		layers: layers,
+		flat:   flattenSettings("", v.AllSettings(), nil),
+	}, nil
+}
+
+// withOverrides 基于当前快照生成一个增加了覆盖值的新快照，文件层不会重新读取
+func (s *settingsSnapshot) withOverrides(overrides map[string]interface{}) (*settingsSnapshot, error) {
+	layers := make(map[ConfigLayer]map[string]interface{}, len(s.layers))
+	for layer, values := range s.layers {
+		layers[layer] = values
+	}
+	layers[LayerOverride] = overrides
+	next, err := newSettingsSnapshot(layers)
+	if err != nil {
+		return nil, err
+	}
+	next.commons = s.commons
+	return next, nil
+}
+
+// forEnv 返回 env 视图使用的快照，环境配置文件中的 common.* 与环境变量层按 env 重新计算，
+// 其他层与当前快照共享，版本号与当前快照一致
+func (s *settingsSnapshot) forEnv(env string, envPrefix string) *settingsSnapshot {
+	if view, ok := s.views.Load(env); ok {
+		return view.(*settingsSnapshot)
+	}
+	layers := make(map[ConfigLayer]map[string]interface{}, len(s.layers))
+	for layer, values := range s.layers {
+		layers[layer] = values
+	}
+	envValues := make(map[string]interface{}, len(s.layers[LayerEnvFile]))
+	for key, val := range s.layers[LayerEnvFile] {
+		if !strings.HasPrefix(key, "common.") {
+			envValues[key] = val
+		}
+	}
+	for key, val := range s.commons[env] {
+		envValues[key] = val
+	}
+	layers[LayerEnvFile] = envValues
+	layers[LayerEnvVar] = envVarLayer(envPrefix, env, layers)
+	view, err := newSettingsSnapshot(layers)
+	if err != nil {
+		GetLogger("settings").Errorf("build settings of %s failed:%v", env, err)
+		return s
+	}
+	view.revision = s.revision
+	view.commons = s.commons
+	actual, _ := s.views.LoadOrStore(env, view)
+	return actual.(*settingsSnapshot)
+}
+
+// readSettingsFile 读取单个yaml文件，optional 为 true 时文件不存在不报错
+func readSettingsFile(path string, optional bool) (map[string]interface{}, error) {
+	if _, err := os.Stat(path); optional && os.IsNotExist(err) {
+		return nil, nil
+	}
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("read settings file %s failed:%w", path, err)
+	}
+	return v.AllSettings(), nil
+}
+
+// flattenSettings 将嵌套的配置展开为 a.b.c 形式的key
+func flattenSettings(prefix string, src map[string]interface{}, dst map[string]interface{}) map[string]interface{} {
+	if dst == nil {
+		dst = make(map[string]interface{})
+	}
+	for k, v := range src {
+		key := strings.ToLower(k)
+		if prefix != "" {
+			key = fmt.Sprintf("%s.%s", prefix, key)
+		}
+		if sub, ok := v.(map[string]interface{}); ok && len(sub) > 0 {
+			flattenSettings(key, sub, dst)
+			continue
+		}
+		dst[key] = v
+	}
+	return dst
+}
+
+// expandSettings 将 a.b.c 形式的key还原为嵌套结构
+func expandSettings(flat map[string]interface{}) map[string]interface{} {
+	dst := make(map[string]interface{})
+	for key, val := range flat {
+		parts := strings.Split(key, ".")
+		node := dst
+		for _, part := range parts[:len(parts)-1] {
+			next, ok := node[part].(map[string]interface{})
+			if !ok {
+				next = make(map[string]interface{})
+				node[part] = next
+			}
+			node = next
+		}
+		node[parts[len(parts)-1]] = val
+	}
+	return dst
+}
+
+// scopeEnvSettings 环境配置文件中除 common 和 <env> 以外的顶层key都挂到 <env> 下
+func scopeEnvSettings(env string, flat map[string]interface{}) map[string]interface{} {
+	dst := make(map[string]interface{}, len(flat))
+	for key, val := range flat {
+		if !strings.HasPrefix(key, env+".") && !strings.HasPrefix(key, "common.") {
+			key = fmt.Sprintf("%s.%s", env, key)
+		}
+		dst[key] = val
+	}
+	return dst
+}
+
+// envVarName 根据配置key计算环境变量名,
+// 例如 dev.mysql.table_prefix => TIGA_MYSQL_TABLE_PREFIX，common.log.level => TIGA_COMMON_LOG_LEVEL
+func envVarName(prefix string, env string, key string) string {
+	key = strings.TrimPrefix(key, env+".")
+	replacer := strings.NewReplacer(".", "_", "-", "_")
+	return fmt.Sprintf("%s_%s", prefix, strings.ToUpper(replacer.Replace(key)))
+}
+
+// lookupEnvVars 查找覆盖已知配置key的环境变量，只有文件中出现过的key才能被环境变量覆盖
+func lookupEnvVars(prefix string, env string, keys []string) map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, key := range keys {
+		if !strings.HasPrefix(key, env+".") && !strings.HasPrefix(key, "common.") {
+			continue
+		}
+		if val, ok := os.LookupEnv(envVarName(prefix, env, key)); ok {
+			values[key] = val
+		}
+	}
+	return values
+}
+
+// normalizeKey 与 Configuration.Get 相同的key补全规则
+func (c Configuration) normalizeKey(key string) string {
+	key = strings.ToLower(strings.TrimPrefix(key, fmt.Sprintf("%s.", c.env)))
+	if !strings.HasPrefix(key, "common") {
+		key = fmt.Sprintf("%s.%s", c.env, key)
+	}
+	return key
+}
+
+// envSettingsFiles 返回配置目录下所有 settings.<env>.yaml，key 为环境名
+func envSettingsFiles(dir string, opts settingsOptions) (map[string]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "settings.*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]string, len(paths))
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".yaml")
+		if name == opts.localName {
+			continue
+		}
+		files[strings.TrimPrefix(name, "settings.")] = path
+	}
+	return files, nil
+}
+
+// readLayers 读取文件层与环境变量层，远程层与覆盖层由调用方维护。
+// 所有环境的 settings.<env>.yaml 都会按环境名顺序加载，其中的 common.* 只取当前环境的文件，
+// 其他环境的文件不会覆盖当前环境的公共配置，各环境文件中的 common.* 按环境名另外返回，见 settingsSnapshot.forEnv；
+// 环境变量只作用于 env
+func readLayers(dir string, env string, opts settingsOptions, remote map[string]interface{}) (map[ConfigLayer]map[string]interface{}, map[string]map[string]interface{}, error) {
+	base, err := readSettingsFile(filepath.Join(dir, "settings.yaml"), false)
+	if err != nil {
+		return nil, nil, err
+	}
+	envFiles, err := envSettingsFiles(dir, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	names := make([]string, 0, len(envFiles))
+	for name := range envFiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	envValues := make(map[string]interface{})
+	commons := make(map[string]map[string]interface{}, len(names))
+	for _, name := range names {
+		envFile, err := readSettingsFile(envFiles[name], true)
+		if err != nil {
+			return nil, nil, err
+		}
+		commons[name] = make(map[string]interface{})
+		for key, val := range scopeEnvSettings(name, flattenSettings("", envFile, nil)) {
+			if strings.HasPrefix(key, "common.") {
+				commons[name][key] = val
+				if name != env {
+					continue
+				}
+			}
+			envValues[key] = val
+		}
+	}
+	local, err := readSettingsFile(filepath.Join(dir, opts.localName+".yaml"), true)
+	if err != nil {
+		return nil, nil, err
+	}
+	layers := map[ConfigLayer]map[string]interface{}{
+		LayerBase:    flattenSettings("", base, nil),
+		LayerEnvFile: envValues,
+		LayerRemote:  remote,
+		LayerLocal:   flattenSettings("", local, nil),
+	}
+	layers[LayerEnvVar] = envVarLayer(opts.envPrefix, env, layers)
+	return layers, commons, nil
+}
+
+// envVarLayer 根据文件层与远程层中出现过的key计算 env 的环境变量层
+func envVarLayer(prefix string, env string, layers map[ConfigLayer]map[string]interface{}) map[string]interface{} {
+	keys := make([]string, 0)
+	for _, layer := range []ConfigLayer{LayerBase, LayerEnvFile, LayerRemote, LayerLocal} {
+		for key := range layers[layer] {
+			keys = append(keys, key)
+		}
+	}
+	return lookupEnvVars(prefix, env, keys)
+}
+
+// Override 以最高优先级显式覆盖某个配置，变更会通知订阅者
+func (c Configuration) Override(key string, value interface{}) error {
+	return c.setOverrides(map[string]interface{}{c.normalizeKey(key): value})
+}
+
+// Origin 返回key当前生效值所在的配置层
+func (c Configuration) Origin(key string) (ConfigLayer, bool) {
+	snapshot := c.snapshot()
+	candidates := []string{c.normalizeKey(key), strings.ToLower(key)}
+	for _, candidate := range candidates {
+		for i := len(layerOrder) - 1; i >= 0; i-- {
+			values := snapshot.layers[layerOrder[i]]
+			if _, ok := values[candidate]; ok {
+				return layerOrder[i], true
+			}
+			if hasChildKey(values, candidate) {
+				return layerOrder[i], true
+			}
+		}
+	}
+	return "", false
+}
+
+// LayerValues 返回某一层中 key 及其下级的扁平配置，key 规则与 Get 一致，
+// 返回的key与 Effective 相同，<env>. 前缀会被去掉
+func (c Configuration) LayerValues(layer ConfigLayer, key string) map[string]interface{} {
+	key = c.normalizeKey(key)
+	values := make(map[string]interface{})
+	for k, val := range c.snapshot().layers[layer] {
+		if k == key || strings.HasPrefix(k, key+".") {
+			values[strings.TrimPrefix(k, c.env+".")] = val
+		}
+	}
+	return values
+}
+
+// hasChildKey 判断key是否为某个配置分支(例如 dev.mysql)
+func hasChildKey(values map[string]interface{}, key string) bool {
+	prefix := key + "."
+	for k := range values {
+		if strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+	return false
+}
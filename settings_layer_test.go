@@ -0,0 +1,31 @@
+package tiga
+
+import "testing"
+
+func TestWithEnvUsesItsOwnCommonAndEnvVars(t *testing.T) {
+	t.Setenv("TIGA_API_URL", "http://env")
+	config := newTestSettings(t, "dev", map[string]string{
+		"settings.yaml":      "common:\n  region: base\n  zone: a\n",
+		"settings.dev.yaml":  "common:\n  region: dev\napi:\n  url: http://dev\n",
+		"settings.prod.yaml": "common:\n  region: prod\napi:\n  url: http://prod\n",
+	})
+	if region := config.GetString("common.region"); region != "dev" {
+		t.Fatalf("expected dev common value, got %q", region)
+	}
+	prod := config.WithEnv("prod")
+	if region := prod.GetString("common.region"); region != "prod" {
+		t.Fatalf("expected prod common value in prod view, got %q", region)
+	}
+	if zone := prod.GetString("common.zone"); zone != "a" {
+		t.Fatalf("expected base common value in prod view, got %q", zone)
+	}
+	if layer, _ := prod.Origin("common.region"); layer != LayerEnvFile {
+		t.Fatalf("expected common.region from env file, got %s", layer)
+	}
+	if url := prod.GetString("api.url"); url != "http://env" {
+		t.Fatalf("expected env var to override prod view, got %q", url)
+	}
+	if url := config.GetString("api.url"); url != "http://env" {
+		t.Fatalf("expected env var to override dev, got %q", url)
+	}
+}
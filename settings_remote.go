@@ -0,0 +1,138 @@
+package tiga
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RemoteSource 远程配置源，返回的配置均为 <env>.a.b 形式的扁平key
+type RemoteSource interface {
+	// Load 读取全部配置
+	Load(ctx context.Context) (map[string]interface{}, error)
+	// Watch 持续监听配置变更，每次变更后以完整的配置回调 onChange，直到 ctx 结束
+	Watch(ctx context.Context, onChange func(values map[string]interface{})) error
+}
+
+const (
+	// remoteLoadTimeout 启动时读取远程配置的超时时间
+	remoteLoadTimeout = 5 * time.Second
+	// remoteCacheName 远程配置本地快照的默认文件名
+	remoteCacheName = ".settings.remote.json"
+)
+
+// WithRemoteSource 使用远程配置源，优先级高于配置文件，低于本地覆盖文件
+func WithRemoteSource(source RemoteSource) SettingsOption {
+	return func(o *settingsOptions) {
+		o.remote = source
+	}
+}
+
+// WithRemoteCache 设置远程配置本地快照路径，默认为配置目录下的 .settings.remote.json，
+// 启动时远程配置源不可用则使用该快照
+func WithRemoteCache(path string) SettingsOption {
+	return func(o *settingsOptions) {
+		o.remoteCache = path
+	}
+}
+
+func (s *configStore) remoteCachePath() string {
+	if s.opts.remoteCache != "" {
+		return s.opts.remoteCache
+	}
+	return filepath.Join(s.dir, remoteCacheName)
+}
+
+// saveRemoteCache 原子写入远程配置快照
+func (s *configStore) saveRemoteCache(values map[string]interface{}) error {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	path := s.remoteCachePath()
+	tmp := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (s *configStore) loadRemoteCache() (map[string]interface{}, error) {
+	data, err := os.ReadFile(s.remoteCachePath())
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]interface{})
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("parse remote settings cache failed:%w", err)
+	}
+	return values, nil
+}
+
+// loadRemote 启动时读取远程配置，失败时退回到本地快照
+func (s *configStore) loadRemote() error {
+	ctx, cancel := context.WithTimeout(context.Background(), remoteLoadTimeout)
+	defer cancel()
+	values, err := s.opts.remote.Load(ctx)
+	if err == nil {
+		if cacheErr := s.saveRemoteCache(values); cacheErr != nil {
+			GetLogger("settings").Warnf("save remote settings cache failed:%v", cacheErr)
+		}
+		s.remote = values
+		return nil
+	}
+	cached, cacheErr := s.loadRemoteCache()
+	if cacheErr != nil {
+		return fmt.Errorf("load remote settings failed:%w, and no usable local cache:%v", err, cacheErr)
+	}
+	GetLogger("settings").Warnf("load remote settings failed, use local cache %s:%v", s.remoteCachePath(), err)
+	s.remote = cached
+	return nil
+}
+
+// applyRemote 远程配置变更后重建快照，走与文件重载相同的校验与通知流程
+func (s *configStore) applyRemote(values map[string]interface{}) {
+	log := GetLogger("settings")
+	s.mu.Lock()
+	layers := make(map[ConfigLayer]map[string]interface{})
+	for layer, vals := range s.snapshot.Load().layers {
+		layers[layer] = vals
+	}
+	layers[LayerRemote] = copySettings(values)
+	next, err := newSettingsSnapshot(layers)
+	if err != nil {
+		s.mu.Unlock()
+		log.Errorf("apply remote settings failed:%v", err)
+		return
+	}
+	next.commons = s.snapshot.Load().commons
+	old, changes, err := s.commit(next)
+	if err != nil {
+		s.mu.Unlock()
+		log.Errorf("apply remote settings failed, keep the last good settings:%v", err)
+		return
+	}
+	s.remote = copySettings(values)
+	s.enqueue(old, next, changes)
+	s.mu.Unlock()
+	if err := s.saveRemoteCache(values); err != nil {
+		log.Warnf("save remote settings cache failed:%v", err)
+	}
+	s.dispatch()
+}
+
+// watchRemote 在后台监听远程配置源
+func (s *configStore) watchRemote() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	s.stopRemote = cancel
+	s.mu.Unlock()
+	go func() {
+		if err := s.opts.remote.Watch(ctx, s.applyRemote); err != nil && ctx.Err() == nil {
+			GetLogger("settings").Errorf("watch remote settings stopped:%v", err)
+		}
+	}()
+}
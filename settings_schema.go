@@ -0,0 +1,209 @@
+package tiga
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+// SchemaField 配置项声明，Type 可选 string、int、float、bool、duration、list、map
+type SchemaField struct {
+	Type     string
+	Required bool
+}
+
+// SettingsSchema 配置声明，key 与 Configuration.Get 的规则一致(不含 env 前缀)
+type SettingsSchema map[string]SchemaField
+
+// SchemaError 汇总配置与声明不一致的配置项
+type SchemaError struct {
+	Env    string
+	Fields []ConfigFieldError
+}
+
+func (e *SchemaError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, fmt.Sprintf("%s %s", f.Key, f.Reason))
+	}
+	return fmt.Sprintf("settings of %s do not match schema: %s", e.Env, strings.Join(msgs, "; "))
+}
+
+var schemaTypes = []string{"string", "int", "float", "bool", "duration", "list", "map"}
+
+// LoadSettingsSchema 读取yaml格式的配置声明，叶子节点为 "类型" 或 "类型,required"，例如:
+//
+//	mysql:
+//	  host: string,required
+//	  port: int
+func LoadSettingsSchema(path string) (SettingsSchema, error) {
+	raw, err := readSettingsFile(path, false)
+	if err != nil {
+		return nil, err
+	}
+	schema := make(SettingsSchema)
+	for key, val := range flattenSettings("", raw, nil) {
+		spec, ok := val.(string)
+		if !ok {
+			return nil, fmt.Errorf("schema %s: expected a type declaration but got %v", key, val)
+		}
+		parts := strings.Split(spec, ",")
+		field := SchemaField{Type: strings.TrimSpace(parts[0])}
+		if !ArrayContainsString(schemaTypes, field.Type) {
+			return nil, fmt.Errorf("schema %s: unknown type %s", key, field.Type)
+		}
+		for _, opt := range parts[1:] {
+			if strings.TrimSpace(opt) == "required" {
+				field.Required = true
+			}
+		}
+		schema[key] = field
+	}
+	return schema, nil
+}
+
+// CheckSchema 校验当前环境的配置，返回 *SchemaError 列出所有缺失或类型错误的配置项
+func (c Configuration) CheckSchema(schema SettingsSchema) error {
+	keys := make([]string, 0, len(schema))
+	for key := range schema {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	schemaErr := &SchemaError{Env: c.env}
+	for _, key := range keys {
+		field := schema[key]
+		_, val := c.getRaw(key)
+		if val == nil {
+			if field.Required {
+				schemaErr.Fields = append(schemaErr.Fields, ConfigFieldError{Key: key, Reason: "is missing"})
+			}
+			continue
+		}
+		if !matchSchemaType(field.Type, val) {
+			schemaErr.Fields = append(schemaErr.Fields, ConfigFieldError{Key: key, Reason: fmt.Sprintf("expected %s but got %v (%T)", field.Type, val, val)})
+		}
+	}
+	if len(schemaErr.Fields) > 0 {
+		return schemaErr
+	}
+	return nil
+}
+
+// matchSchemaType 判断配置值是否可以作为声明的类型使用，环境变量等字符串形式的值按可转换处理
+func matchSchemaType(typ string, val interface{}) bool {
+	kind := reflect.ValueOf(val).Kind()
+	switch typ {
+	case "string":
+		return kind != reflect.Map && kind != reflect.Slice
+	case "int":
+		f, err := cast.ToFloat64E(val)
+		return err == nil && f == math.Trunc(f)
+	case "float":
+		_, err := cast.ToFloat64E(val)
+		return err == nil
+	case "bool":
+		_, err := cast.ToBoolE(val)
+		return err == nil
+	case "duration":
+		_, err := cast.ToDurationE(val)
+		return err == nil
+	case "list":
+		return kind == reflect.Slice
+	case "map":
+		return kind == reflect.Map
+	}
+	return false
+}
+
+// IsSecretKey 根据key名判断是否为敏感配置，例如 *.password、*.token
+func IsSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	if idx := strings.LastIndex(key, "."); idx >= 0 {
+		key = key[idx+1:]
+	}
+	for _, word := range []string{"password", "passwd", "secret", "token", "master_key", "private_key"} {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaskSecrets 将敏感配置以及密文引用替换为 ******
+func MaskSecrets(values map[string]interface{}) map[string]interface{} {
+	masked := make(map[string]interface{}, len(values))
+	for key, val := range values {
+		if IsSecretKey(key) || IsSecretRef(val) {
+			val = "******"
+		}
+		masked[key] = val
+	}
+	return masked
+}
+
+// Effective 返回当前环境最终生效的扁平配置，<env>. 前缀会被去掉，common. 下的配置保持原样，
+// 密文引用不会被解析
+func (c Configuration) Effective() map[string]interface{} {
+	values := make(map[string]interface{})
+	for key, val := range c.snapshot().flat {
+		if strings.HasPrefix(key, "common.") {
+			values[key] = val
+		}
+	}
+	for key, val := range c.snapshot().flat {
+		if strings.HasPrefix(key, c.env+".") {
+			values[strings.TrimPrefix(key, c.env+".")] = val
+		}
+	}
+	return values
+}
+
+// GetScoped 只查找当前环境的 <env>.key 与 common.* 配置，不会像 Get 一样回退到原始key，
+// 其他环境的key返回 nil，值中的密文引用会被解析
+func (c Configuration) GetScoped(key string) interface{} {
+	key = strings.TrimPrefix(strings.ToLower(key), c.env+".")
+	if !strings.HasPrefix(key, "common.") {
+		key = fmt.Sprintf("%s.%s", c.env, key)
+	}
+	return c.resolve(key, c.viper().Get(key))
+}
+
+// Envs 返回配置中出现的环境名，即除 common 以外的顶层key
+func (c Configuration) Envs() []string {
+	seen := make(map[string]bool)
+	for key := range c.snapshot().flat {
+		env, _, _ := strings.Cut(key, ".")
+		if env != "common" && env != key {
+			seen[env] = true
+		}
+	}
+	envs := make([]string, 0, len(seen))
+	for env := range seen {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+	return envs
+}
+
+// DiffSettings 逐个key对比两份扁平配置
+func DiffSettings(old map[string]interface{}, new map[string]interface{}) []ConfigChange {
+	changes := make([]ConfigChange, 0)
+	for key, val := range new {
+		if oldVal, ok := old[key]; !ok || !reflect.DeepEqual(oldVal, val) {
+			changes = append(changes, ConfigChange{Key: key, Old: oldVal, New: val})
+		}
+	}
+	for key, val := range old {
+		if _, ok := new[key]; !ok {
+			changes = append(changes, ConfigChange{Key: key, Old: val})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
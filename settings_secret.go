@@ -0,0 +1,162 @@
+package tiga
+
+import (
+	"crypto/aes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// 配置值中支持的密文引用:
+//
+//	enc:AES:<hex>        使用主密钥解密，<hex> 为 EncryptAESWithAutoIV 的输出
+//	env:VAR_NAME         读取环境变量
+//	file:/run/secrets/x  读取文件内容，去掉末尾换行
+//	raw:xxx              原样返回 xxx，用于值本身以上述前缀开头的情况
+const (
+	secretEncPrefix  = "enc:AES:"
+	secretEnvPrefix  = "env:"
+	secretFilePrefix = "file:"
+	secretRawPrefix  = "raw:"
+)
+
+// MasterKeyEnv 主密钥环境变量名，例如 TIGA_MASTER_KEY，密钥长度必须为 16、24 或 32 字节
+func MasterKeyEnv(prefix string) string {
+	return fmt.Sprintf("%s_MASTER_KEY", prefix)
+}
+
+// EncryptSecret 加密配置值，返回可直接写入 settings.yaml 的 enc:AES:<hex>
+func EncryptSecret(masterKey []byte, plaintext string) (string, error) {
+	cipherHex, err := EncryptAESWithAutoIV(masterKey, plaintext)
+	if err != nil {
+		return "", fmt.Errorf("encrypt secret failed:%w", err)
+	}
+	return secretEncPrefix + cipherHex, nil
+}
+
+// DecryptSecret 解密 enc:AES:<hex> 形式的配置值
+func DecryptSecret(masterKey []byte, value string) (string, error) {
+	cipherHex := strings.TrimPrefix(value, secretEncPrefix)
+	if len(cipherHex) < aes.BlockSize*2 {
+		return "", fmt.Errorf("invalid encrypted value")
+	}
+	iv, err := hex.DecodeString(cipherHex[:aes.BlockSize*2])
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value iv:%w", err)
+	}
+	plaintext, err := DecryptAES(masterKey, cipherHex[aes.BlockSize*2:], string(iv))
+	if err != nil {
+		return "", fmt.Errorf("decrypt secret failed:%w", err)
+	}
+	return plaintext, nil
+}
+
+// IsSecretRef 判断配置值是否为密文引用
+func IsSecretRef(value interface{}) bool {
+	str, ok := value.(string)
+	if !ok {
+		return false
+	}
+	for _, prefix := range []string{secretEncPrefix, secretEnvPrefix, secretFilePrefix} {
+		if strings.HasPrefix(str, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveSecret 解析单个配置值中的密文引用，非引用的值原样返回
+func resolveSecret(value string, envPrefix string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, secretRawPrefix):
+		return strings.TrimPrefix(value, secretRawPrefix), nil
+	case strings.HasPrefix(value, secretEncPrefix):
+		key, ok := os.LookupEnv(MasterKeyEnv(envPrefix))
+		if !ok || key == "" {
+			return "", fmt.Errorf("master key %s is not set", MasterKeyEnv(envPrefix))
+		}
+		return DecryptSecret([]byte(key), value)
+	case strings.HasPrefix(value, secretEnvPrefix):
+		name := strings.TrimPrefix(value, secretEnvPrefix)
+		val, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return val, nil
+	case strings.HasPrefix(value, secretFilePrefix):
+		path := strings.TrimPrefix(value, secretFilePrefix)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("read secret file %s failed:%w", path, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	return value, nil
+}
+
+// resolveSecrets 递归解析配置值中的密文引用，结果按快照缓存，重载后重新解析
+func (s *settingsSnapshot) resolveSecrets(value interface{}, envPrefix string) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if !IsSecretRef(v) && !strings.HasPrefix(v, secretRawPrefix) {
+			return v, nil
+		}
+		if cached, ok := s.secrets.Load(v); ok {
+			return cached, nil
+		}
+		resolved, err := resolveSecret(v, envPrefix)
+		if err != nil {
+			return nil, err
+		}
+		s.secrets.Store(v, resolved)
+		return resolved, nil
+	case map[string]interface{}:
+		dst := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			resolved, err := s.resolveSecrets(val, envPrefix)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%w", key, err)
+			}
+			dst[key] = resolved
+		}
+		return dst, nil
+	case []interface{}:
+		dst := make([]interface{}, len(v))
+		for i, val := range v {
+			resolved, err := s.resolveSecrets(val, envPrefix)
+			if err != nil {
+				return nil, err
+			}
+			dst[i] = resolved
+		}
+		return dst, nil
+	}
+	return value, nil
+}
+
+// resolve 解析配置值中的密文引用，失败时记录日志并返回 nil
+func (c Configuration) resolve(key string, value interface{}) interface{} {
+	resolved, err := c.resolveE(value)
+	if err != nil {
+		GetLogger("settings").Errorf("resolve secret %s failed:%v", key, err)
+		return nil
+	}
+	return resolved
+}
+
+// ResolveSecrets 解析配置值(包括嵌套的map与列表)中的密文引用
+func (c Configuration) ResolveSecrets(value interface{}) (interface{}, error) {
+	return c.resolveE(value)
+}
+
+func (c Configuration) resolveE(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	envPrefix := DefaultEnvPrefix
+	if c.store != nil {
+		envPrefix = c.store.opts.envPrefix
+	}
+	return c.snapshot().resolveSecrets(value, envPrefix)
+}
@@ -0,0 +1,401 @@
+package tiga
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ConfigValidator 在新的配置生效之前对其进行校验，返回错误时新配置会被丢弃
+type ConfigValidator func(next *Configuration) error
+
+// ConfigChange 单个配置key的变更
+type ConfigChange struct {
+	Key string
+	Old interface{}
+	New interface{}
+}
+
+type configSubscriber struct {
+	// env 订阅所在视图的环境
+	env    string
+	prefix string
+	fn     func(old, new any)
+	// changes 非空时以完整的变更列表回调，用于需要逐个key处理变更的场景
+	changes func(revision uint64, changes []ConfigChange)
+}
+
+// configStore 多个 Configuration 视图共享的配置数据
+type configStore struct {
+	// mu 串行化所有写操作，读操作只通过 snapshot 原子读取
+	mu       sync.Mutex
+	snapshot atomic.Pointer[settingsSnapshot]
+	dir      string
+	// env 创建配置时确定的环境，其他环境的 WithEnv 视图共享同一份数据，common.* 与环境变量层见 settingsSnapshot.forEnv
+	env         string
+	opts        settingsOptions
+	overrides   map[string]interface{}
+	remote      map[string]interface{}
+	validators  []ConfigValidator
+	subscribers map[uint64]*configSubscriber
+	nextID      uint64
+	revision    uint64
+	watcher     *fsnotify.Watcher
+	// stopRemote 停止监听远程配置源
+	stopRemote context.CancelFunc
+	// pending 待分发的变更通知，在持有 mu 时按版本号顺序加入
+	pending     []*changeNotification
+	dispatching bool
+}
+
+// changeNotification 一次生效的变更及其需要通知的订阅者
+type changeNotification struct {
+	subs    []*configSubscriber
+	old     *settingsSnapshot
+	next    *settingsSnapshot
+	changes []ConfigChange
+}
+
+func newConfigStore(dir string, env string, opts settingsOptions) *configStore {
+	store := &configStore{
+		dir:         dir,
+		env:         env,
+		opts:        opts,
+		overrides:   make(map[string]interface{}),
+		remote:      make(map[string]interface{}),
+		subscribers: make(map[uint64]*configSubscriber),
+	}
+	store.snapshot.Store(emptySnapshot)
+	return store
+}
+
+// diffSnapshots 对比两个快照中所有叶子节点，返回按key排序的变更列表
+func diffSnapshots(old *settingsSnapshot, next *settingsSnapshot) []ConfigChange {
+	return DiffSettings(old.flat, next.flat)
+}
+
+// matchPrefix 判断变更的key是否落在订阅的前缀下
+func matchPrefix(prefix string, key string) bool {
+	return prefix == "" || key == prefix || strings.HasPrefix(key, prefix+".") || strings.HasPrefix(prefix, key+".")
+}
+
+// commit 校验并原子替换快照，调用方必须持有 mu
+func (s *configStore) commit(next *settingsSnapshot) (*settingsSnapshot, []ConfigChange, error) {
+	view := &Configuration{env: s.env, store: &configStore{env: s.env}}
+	view.store.snapshot.Store(next)
+	for _, validate := range s.validators {
+		if err := validate(view); err != nil {
+			return nil, nil, fmt.Errorf("validate settings failed:%w", err)
+		}
+	}
+	old := s.snapshot.Load()
+	changes := diffSnapshots(old, next)
+	if len(changes) == 0 && old != emptySnapshot {
+		// 生效值没有变化时版本号不变，但各层的值可能已经变化，仍然替换快照使 Origin 等保持准确
+		next.revision = old.revision
+		s.snapshot.Store(next)
+		return old, changes, nil
+	}
+	s.revision++
+	next.revision = s.revision
+	s.snapshot.Store(next)
+	return old, changes, nil
+}
+
+// listSubscribers 按订阅顺序返回订阅者，调用方必须持有 mu
+func (s *configStore) listSubscribers() []*configSubscriber {
+	ids := make([]uint64, 0, len(s.subscribers))
+	for id := range s.subscribers {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	subs := make([]*configSubscriber, 0, len(ids))
+	for _, id := range ids {
+		subs = append(subs, s.subscribers[id])
+	}
+	return subs
+}
+
+// enqueue 将变更加入待分发队列，调用方必须持有 mu，因此队列中的通知与版本号顺序一致
+func (s *configStore) enqueue(old *settingsSnapshot, next *settingsSnapshot, changes []ConfigChange) {
+	if len(changes) == 0 {
+		return
+	}
+	s.pending = append(s.pending, &changeNotification{subs: s.listSubscribers(), old: old, next: next, changes: changes})
+}
+
+// dispatch 在锁外按版本号顺序分发队列中的通知。同一时间只有一个 goroutine 分发，
+// 其他 goroutine 加入的通知由正在分发的 goroutine 依次回调，不会乱序也不会并发回调
+func (s *configStore) dispatch() {
+	s.mu.Lock()
+	if s.dispatching {
+		s.mu.Unlock()
+		return
+	}
+	s.dispatching = true
+	for len(s.pending) > 0 {
+		n := s.pending[0]
+		s.pending = s.pending[1:]
+		s.mu.Unlock()
+		s.notify(n.subs, n.old, n.next, n.changes)
+		s.mu.Lock()
+	}
+	s.dispatching = false
+	s.mu.Unlock()
+}
+
+// notify 在锁外回调订阅者，订阅者中可以安全地读取或修改配置
+func (s *configStore) notify(subs []*configSubscriber, old *settingsSnapshot, next *settingsSnapshot, changes []ConfigChange) {
+	if len(changes) == 0 {
+		return
+	}
+	for _, sub := range subs {
+		if sub.changes != nil {
+			s.invoke(sub, func() { sub.changes(next.revision, changes) })
+			continue
+		}
+		for _, change := range changes {
+			if matchPrefix(sub.prefix, change.Key) {
+				s.invoke(sub, func() { sub.fn(s.value(old, sub.env, sub.prefix), s.value(next, sub.env, sub.prefix)) })
+				break
+			}
+		}
+	}
+}
+
+// view 返回 env 视图读取的快照
+func (s *configStore) view(snapshot *settingsSnapshot, env string) *settingsSnapshot {
+	if env == s.env {
+		return snapshot
+	}
+	return snapshot.forEnv(env, s.opts.envPrefix)
+}
+
+// value 读取 env 视图中 key 的值并解析密文引用，与 Get 的结果一致，解析失败时记录日志并返回 nil
+func (s *configStore) value(snapshot *settingsSnapshot, env string, key string) interface{} {
+	snapshot = s.view(snapshot, env)
+	val, err := snapshot.resolveSecrets(snapshot.v.Get(key), s.opts.envPrefix)
+	if err != nil {
+		GetLogger("settings").Errorf("resolve secret %s failed:%v", key, err)
+		return nil
+	}
+	return val
+}
+
+func (s *configStore) invoke(sub *configSubscriber, fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			GetLogger("settings").Errorf("config subscriber %s panic:%v", sub.prefix, r)
+		}
+	}()
+	fn()
+}
+
+// reload 重新读取文件层，保留已有的覆盖值
+func (s *configStore) reload() ([]ConfigChange, error) {
+	s.mu.Lock()
+	layers, commons, err := readLayers(s.dir, s.env, s.opts, copySettings(s.remote))
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	layers[LayerOverride] = copySettings(s.overrides)
+	next, err := newSettingsSnapshot(layers)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	next.commons = commons
+	old, changes, err := s.commit(next)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	s.enqueue(old, next, changes)
+	s.mu.Unlock()
+	s.dispatch()
+	return changes, nil
+}
+
+func copySettings(src map[string]interface{}) map[string]interface{} {
+	dst := make(map[string]interface{}, len(src))
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
+
+func (c Configuration) setOverrides(values map[string]interface{}) error {
+	if c.store == nil {
+		return fmt.Errorf("configuration is not initialized")
+	}
+	s := c.store
+	s.mu.Lock()
+	overrides := copySettings(s.overrides)
+	for k, v := range values {
+		overrides[k] = v
+	}
+	next, err := s.snapshot.Load().withOverrides(copySettings(overrides))
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	old, changes, err := s.commit(next)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.overrides = overrides
+	s.enqueue(old, next, changes)
+	s.mu.Unlock()
+	s.dispatch()
+	return nil
+}
+
+// Subscribe 订阅 keyPrefix 下的配置变更，key 规则与 Get 一致。
+// 只有前缀下确实有值发生变化时才会回调，old/new 为变更前后 keyPrefix 对应的值，其中的密文引用已解析，与 Get 的结果一致。
+// 返回的函数用于取消订阅
+func (c Configuration) Subscribe(keyPrefix string, fn func(old, new any)) func() {
+	if c.store == nil {
+		return func() {}
+	}
+	prefix := ""
+	if keyPrefix != "" {
+		prefix = c.normalizeKey(keyPrefix)
+	}
+	return c.store.subscribe(&configSubscriber{env: c.env, prefix: prefix, fn: fn})
+}
+
+// SubscribeChanges 订阅所有配置变更，每次生效的变更以新的版本号和按key排序的变更列表回调一次，
+// key 为包含环境前缀的完整key，值中的密文引用不会被解析。返回的函数用于取消订阅
+func (c Configuration) SubscribeChanges(fn func(revision uint64, changes []ConfigChange)) func() {
+	if c.store == nil {
+		return func() {}
+	}
+	return c.store.subscribe(&configSubscriber{changes: fn})
+}
+
+func (s *configStore) subscribe(sub *configSubscriber) func() {
+	s.mu.Lock()
+	s.nextID++
+	id := s.nextID
+	s.subscribers[id] = sub
+	s.mu.Unlock()
+	return func() {
+		s.mu.Lock()
+		delete(s.subscribers, id)
+		s.mu.Unlock()
+	}
+}
+
+// AddValidator 注册校验函数，之后的每一次重载或覆盖都需要通过校验才会生效
+func (c Configuration) AddValidator(validator ConfigValidator) {
+	if c.store == nil {
+		return
+	}
+	c.store.mu.Lock()
+	c.store.validators = append(c.store.validators, validator)
+	c.store.mu.Unlock()
+}
+
+// Reload 重新读取配置文件，校验失败或文件格式错误时保留当前配置
+func (c Configuration) Reload() ([]ConfigChange, error) {
+	if c.store == nil {
+		return nil, fmt.Errorf("configuration is not initialized")
+	}
+	return c.store.reload()
+}
+
+// Revision 当前配置的版本号，每次生效的变更加一
+func (c Configuration) Revision() uint64 {
+	return c.snapshot().revision
+}
+
+// WatchConfig 监听配置目录，文件变更后自动重载
+func (c Configuration) WatchConfig() error {
+	if c.store == nil {
+		return fmt.Errorf("configuration is not initialized")
+	}
+	s := c.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.watcher != nil {
+		return nil
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("create settings watcher failed:%w", err)
+	}
+	if err := watcher.Add(s.dir); err != nil {
+		watcher.Close()
+		return fmt.Errorf("watch settings dir %s failed:%w", s.dir, err)
+	}
+	s.watcher = watcher
+	go s.watch(watcher)
+	return nil
+}
+
+func (s *configStore) isSettingsFile(name string) bool {
+	base := filepath.Base(name)
+	// k8s configmap 通过替换 ..data 软链接更新文件
+	return base == "..data" || (strings.HasPrefix(base, "settings.") && strings.HasSuffix(base, ".yaml"))
+}
+
+func (s *configStore) watch(watcher *fsnotify.Watcher) {
+	log := GetLogger("settings")
+	// 编辑器保存文件时会产生多个事件，合并后只重载一次
+	var timer *time.Timer
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if !s.isSettingsFile(event.Name) || event.Op == fsnotify.Chmod {
+				continue
+			}
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.AfterFunc(100*time.Millisecond, func() {
+				changes, err := s.reload()
+				if err != nil {
+					log.Errorf("reload settings failed, keep the last good settings:%v", err)
+					return
+				}
+				log.Infof("settings reloaded, %d keys changed", len(changes))
+			})
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Errorf("settings watcher error:%v", err)
+		}
+	}
+}
+
+// Close 停止监听配置文件与远程配置源
+func (c Configuration) Close() error {
+	if c.store == nil {
+		return nil
+	}
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+	if c.store.stopRemote != nil {
+		c.store.stopRemote()
+		c.store.stopRemote = nil
+	}
+	if c.store.watcher == nil {
+		return nil
+	}
+	err := c.store.watcher.Close()
+	c.store.watcher = nil
+	return err
+}
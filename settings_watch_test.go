@@ -0,0 +1,179 @@
+package tiga
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func newTestSettings(t *testing.T, env string, files map[string]string) *Configuration {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s failed:%v", name, err)
+		}
+	}
+	config, err := LoadSettings(env, dir)
+	if err != nil {
+		t.Fatalf("load settings failed:%v", err)
+	}
+	return config
+}
+
+func TestSubscribeChangesInRevisionOrder(t *testing.T) {
+	config := newTestSettings(t, "dev", map[string]string{"settings.yaml": "dev:\n  n: 0\n"})
+	var (
+		mu        sync.Mutex
+		revisions []uint64
+	)
+	config.SubscribeChanges(func(revision uint64, changes []ConfigChange) {
+		mu.Lock()
+		defer mu.Unlock()
+		revisions = append(revisions, revision)
+	})
+	start := config.Revision()
+	var wg sync.WaitGroup
+	for i := 1; i <= 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := config.Override(fmt.Sprintf("k%d", i), i); err != nil {
+				t.Errorf("override failed:%v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	mu.Lock()
+	defer mu.Unlock()
+	if len(revisions) != 50 {
+		t.Fatalf("expected 50 notifications, got %d", len(revisions))
+	}
+	for i, revision := range revisions {
+		if revision != start+uint64(i)+1 {
+			t.Fatalf("notifications out of order: %v", revisions)
+		}
+	}
+}
+
+func TestLayerPrecedence(t *testing.T) {
+	t.Setenv("TIGA_D", "envvar")
+	t.Setenv("TIGA_E", "envvar")
+	config := newTestSettings(t, "dev", map[string]string{
+		"settings.yaml":       "dev:\n  a: base\n  b: base\n  c: base\n  d: base\n  e: base\n",
+		"settings.dev.yaml":   "b: envfile\nc: envfile\nd: envfile\ne: envfile\n",
+		"settings.local.yaml": "dev:\n  c: local\n  d: local\n  e: local\n",
+	})
+	if err := config.Override("e", "override"); err != nil {
+		t.Fatalf("override failed:%v", err)
+	}
+	expected := map[string]struct {
+		value string
+		layer ConfigLayer
+	}{
+		"a": {"base", LayerBase},
+		"b": {"envfile", LayerEnvFile},
+		"c": {"local", LayerLocal},
+		"d": {"envvar", LayerEnvVar},
+		"e": {"override", LayerOverride},
+	}
+	for key, want := range expected {
+		if got := config.GetString(key); got != want.value {
+			t.Errorf("%s: expected %q, got %q", key, want.value, got)
+		}
+		if layer, _ := config.Origin(key); layer != want.layer {
+			t.Errorf("%s: expected layer %s, got %s", key, want.layer, layer)
+		}
+	}
+}
+
+func TestReloadSwapsSnapshotAtomically(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) {
+		if err := os.WriteFile(filepath.Join(dir, "settings.yaml"), []byte(content), 0o644); err != nil {
+			t.Fatalf("write settings failed:%v", err)
+		}
+	}
+	write("dev:\n  x: 0\n  y: 0\n")
+	config, err := LoadSettings("dev", dir)
+	if err != nil {
+		t.Fatalf("load settings failed:%v", err)
+	}
+	config.AddValidator(func(next *Configuration) error {
+		if next.GetInt("x") < 0 {
+			return fmt.Errorf("x must not be negative")
+		}
+		return nil
+	})
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			// 同一次读取只会看到一个完整的快照
+			dev, _ := config.AllSettings()["dev"].(map[string]interface{})
+			if fmt.Sprint(dev["x"]) != fmt.Sprint(dev["y"]) {
+				t.Errorf("read a partially applied snapshot: %v", dev)
+				return
+			}
+		}
+	}()
+	for i := 1; i <= 20; i++ {
+		write(fmt.Sprintf("dev:\n  x: %d\n  y: %d\n", i, i))
+		if _, err := config.Reload(); err != nil {
+			t.Fatalf("reload failed:%v", err)
+		}
+	}
+	close(done)
+	wg.Wait()
+	revision := config.Revision()
+	write("dev:\n  x: -1\n  y: -1\n")
+	if _, err := config.Reload(); err == nil {
+		t.Fatal("expected validation to reject the reload")
+	}
+	if config.GetInt("x") != 20 || config.Revision() != revision {
+		t.Fatalf("rejected reload changed settings: x=%v revision=%d", config.Get("x"), config.Revision())
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	t.Setenv("TEST_DB_PASSWORD", "secret")
+	config := newTestSettings(t, "dev", map[string]string{"settings.yaml": "dev:\n  db:\n    host: h1\n  api:\n    url: http://a\n"})
+	type call struct{ old, new any }
+	calls := make([]call, 0)
+	unsubscribe := config.Subscribe("db", func(old, new any) {
+		calls = append(calls, call{old, new})
+	})
+	if err := config.Override("api.url", "http://b"); err != nil {
+		t.Fatalf("override failed:%v", err)
+	}
+	if len(calls) != 0 {
+		t.Fatalf("expected no callback for other prefixes, got %v", calls)
+	}
+	if err := config.Override("db.password", "env:TEST_DB_PASSWORD"); err != nil {
+		t.Fatalf("override failed:%v", err)
+	}
+	if len(calls) != 1 {
+		t.Fatalf("expected 1 callback, got %d", len(calls))
+	}
+	old, _ := calls[0].old.(map[string]interface{})
+	next, _ := calls[0].new.(map[string]interface{})
+	if old["password"] != nil || next["password"] != "secret" || next["host"] != "h1" {
+		t.Fatalf("unexpected callback values old=%v new=%v", calls[0].old, calls[0].new)
+	}
+	unsubscribe()
+	if err := config.Override("db.host", "h2"); err != nil {
+		t.Fatalf("override failed:%v", err)
+	}
+	if len(calls) != 1 {
+		t.Fatalf("expected no callback after unsubscribe, got %d", len(calls))
+	}
+}
@@ -105,6 +105,10 @@ func (q QueryTags) BuildConditions(base *gorm.DB, conditions interface{}) *gorm.
 			queryTag := typeField.Tag.Get("query")
 			if queryTag != "" {
 				conditions := q.parseQueryTag(queryTag)
+				// 游标字段在遍历结束后统一处理
+				if _, has := conditions["cursor"]; has {
+					continue
+				}
 				// skip为特定的值时，跳过该字段
 				if skip, ok := conditions["skip"]; ok && skip == val.String() {
 					continue
@@ -124,6 +128,14 @@ func (q QueryTags) BuildConditions(base *gorm.DB, conditions interface{}) *gorm.
 			}
 		}
 	}
+	if pagination := q.CursorPagination(conditions); pagination != nil {
+		db, err := pagination.Apply(base)
+		if err != nil {
+			_ = base.AddError(err)
+			return base
+		}
+		return db
+	}
 	if ((int64(page) - 1) *pageSize) > 0 {
 		base = base.Offset((int(page) - 1) * int(pageSize)).Limit(int(pageSize))
 		ok = true
@@ -133,6 +145,41 @@ func (q QueryTags) BuildConditions(base *gorm.DB, conditions interface{}) *gorm.
 	}
 	return base
 }
+// CursorPagination 读取查询条件中带有 query:"cursor:排序字段" 标签的游标字段，
+// 例如 Cursor string `query:"cursor:created_at,id;order:desc"`，每页条数取 PageSize 字段。
+// 没有游标字段时返回 nil，有游标字段时 BuildConditions 使用游标分页代替 Page 与 PageSize 分页，
+// 查询后使用 CursorPagination.Next 生成下一页的游标
+func (q QueryTags) CursorPagination(conditions interface{}) *CursorPagination {
+	val := reflect.ValueOf(conditions)
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < val.NumField(); i++ {
+		typeField := val.Type().Field(i)
+		queryTag := typeField.Tag.Get("query")
+		if typeField.PkgPath != "" || queryTag == "" || val.Field(i).Kind() != reflect.String {
+			continue
+		}
+		tags := q.parseQueryTag(queryTag)
+		keys, ok := tags["cursor"]
+		if !ok {
+			continue
+		}
+		pagination := &CursorPagination{
+			Cursor: val.Field(i).String(),
+			Keys:   strings.Split(keys, ","),
+			Desc:   tags["order"] == "desc",
+		}
+		if pageSize := val.FieldByName("PageSize"); pageSize.IsValid() && pageSize.CanInt() {
+			pagination.PageSize = int32(pageSize.Int())
+		}
+		return pagination
+	}
+	return nil
+}
 func TagsTransformer(object interface{}, srcTagName string, targetTagName string) interface{} {
 	// Get the reflect value of the struct
 	v := reflect.ValueOf(object).Elem()
@@ -0,0 +1,158 @@
+package tiga
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// validateRule validate 标签中的单条规则，例如 required、min=1、oneof=a b
+type validateRule struct {
+	name  string
+	param string
+}
+
+var regexCache sync.Map
+
+// parseValidateTag 解析 validate 标签，regex 规则会吞掉其后的全部内容以便正则中可以包含逗号
+func parseValidateTag(tag string) []validateRule {
+	rules := make([]validateRule, 0)
+	for tag != "" {
+		part := tag
+		if strings.HasPrefix(strings.TrimSpace(tag), "regex=") {
+			tag = ""
+		} else if idx := strings.Index(tag, ","); idx >= 0 {
+			part, tag = tag[:idx], tag[idx+1:]
+		} else {
+			tag = ""
+		}
+		part = strings.TrimSpace(part)
+		if part == "" || part == "-" {
+			continue
+		}
+		name, param, _ := strings.Cut(part, "=")
+		rules = append(rules, validateRule{name: strings.TrimSpace(name), param: param})
+	}
+	return rules
+}
+
+func hasValidateRule(rules []validateRule, name string) bool {
+	for _, rule := range rules {
+		if rule.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validateValue 按规则校验字段值，返回所有不满足的规则描述
+func validateValue(val reflect.Value, rules []validateRule) []string {
+	reasons := make([]string, 0)
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			if hasValidateRule(rules, "required") {
+				reasons = append(reasons, "is required")
+			}
+			return reasons
+		}
+		val = val.Elem()
+	}
+	if !val.IsValid() {
+		if hasValidateRule(rules, "required") {
+			reasons = append(reasons, "is required")
+		}
+		return reasons
+	}
+	for _, rule := range rules {
+		var reason string
+		switch rule.name {
+		case "required":
+			if val.IsZero() {
+				reason = "is required"
+			}
+		case "min", "max", "len":
+			reason = checkBound(val, rule)
+		case "oneof", "enum":
+			options := strings.Fields(strings.ReplaceAll(rule.param, "|", " "))
+			if str := fmt.Sprintf("%v", val.Interface()); !ArrayContainsString(options, str) {
+				reason = fmt.Sprintf("must be one of [%s], got %s", strings.Join(options, " "), str)
+			}
+		case "regex":
+			reason = checkRegex(val, rule.param)
+		case "omitempty":
+			if val.IsZero() {
+				return reasons
+			}
+		default:
+			reason = fmt.Sprintf("unknown validate rule %s", rule.name)
+		}
+		if reason != "" {
+			reasons = append(reasons, reason)
+		}
+	}
+	return reasons
+}
+
+func checkBound(val reflect.Value, rule validateRule) string {
+	limit, err := strconv.ParseFloat(rule.param, 64)
+	if err != nil {
+		return fmt.Sprintf("invalid %s parameter %q", rule.name, rule.param)
+	}
+	var actual float64
+	unit := ""
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		actual = float64(val.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		actual = float64(val.Uint())
+	case reflect.Float32, reflect.Float64:
+		actual = val.Float()
+	case reflect.String:
+		actual = float64(len([]rune(val.String())))
+		unit = "length "
+	case reflect.Slice, reflect.Array, reflect.Map:
+		actual = float64(val.Len())
+		unit = "length "
+	default:
+		return ""
+	}
+	switch rule.name {
+	case "min":
+		if actual < limit {
+			return fmt.Sprintf("%smust be >= %s, got %v", unit, rule.param, actual)
+		}
+	case "max":
+		if actual > limit {
+			return fmt.Sprintf("%smust be <= %s, got %v", unit, rule.param, actual)
+		}
+	case "len":
+		if actual != limit {
+			return fmt.Sprintf("%smust be %s, got %v", unit, rule.param, actual)
+		}
+	}
+	return ""
+}
+
+func checkRegex(val reflect.Value, pattern string) string {
+	if val.Kind() != reflect.String {
+		return ""
+	}
+	var re *regexp.Regexp
+	if cached, ok := regexCache.Load(pattern); ok {
+		re = cached.(*regexp.Regexp)
+	} else {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Sprintf("invalid regex %q", pattern)
+		}
+		regexCache.Store(pattern, compiled)
+		re = compiled
+	}
+	if !re.MatchString(val.String()) {
+		return fmt.Sprintf("must match %s", pattern)
+	}
+	return ""
+}